@@ -36,7 +36,7 @@ func main() {
 	defer syncWorker.Stop()
 
 	// Setup router
-	app := router.SetupRouter()
+	app := router.SetupRouter(syncWorker)
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
@@ -3,8 +3,12 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/robfig/cron/v3"
 )
 
 type Config struct {
@@ -29,8 +33,35 @@ type Config struct {
 	// Frontend
 	FrontendURL string
 
+	// Registries
+	RegistryMaxPages int // Ceiling on pages followed per repository/tag listing, for every registry
+
 	// Docker Hub
-	DockerHubAPIURL string
+	DockerHubAPIURL     string
+	DockerHubMaxRetries int // Retries for throttled (429) or failed (5xx) Docker Hub requests
+
+	// Quay.io
+	QuayAPIURL string
+
+	// Self-hosted (OCI) registries
+	OCIAllowedHosts []string // When set, the only registry hosts (or host:port) the backend may contact
+
+	// Sync
+	SyncConcurrency int // Repositories whose tags are fetched in parallel within one sync
+	SyncWorkers     int // Queued sync jobs run in parallel by each replica
+
+	// Worker
+	SyncScheduleCron string        // When the leader looks for accounts due a scheduled sync
+	CleanupCron      string        // When the leader removes old activity and sync history
+	SyncTimeout      time.Duration // Upper bound on a single account sync
+	SyncMinInterval  time.Duration // Shortest gap between scheduled syncs of the busiest accounts
+	SyncJobDelay     time.Duration // Pause between two syncs of one queue worker, to avoid rate limiting
+
+	// Shutdown
+	ShutdownGracePeriod time.Duration // How long running syncs may finish before they are interrupted
+
+	// Operators
+	AdminToken string // Bearer token for operator endpoints; they are disabled while empty
 }
 
 var AppConfig *Config
@@ -63,8 +94,35 @@ func Load() {
 		// Frontend
 		FrontendURL: getEnv("FRONTEND_URL", "http://localhost:3000"),
 
+		// Registries (DOCKER_HUB_MAX_PAGES is the former name, still honoured)
+		RegistryMaxPages: getEnvInt("REGISTRY_MAX_PAGES", getEnvInt("DOCKER_HUB_MAX_PAGES", 50)),
+
 		// Docker Hub
-		DockerHubAPIURL: getEnv("DOCKER_HUB_API_URL", "https://hub.docker.com/v2"),
+		DockerHubAPIURL:     getEnv("DOCKER_HUB_API_URL", "https://hub.docker.com/v2"),
+		DockerHubMaxRetries: getEnvInt("DOCKER_HUB_MAX_RETRIES", 4),
+
+		// Quay.io
+		QuayAPIURL: getEnv("QUAY_API_URL", "https://quay.io/api/v1"),
+
+		// Self-hosted (OCI) registries
+		OCIAllowedHosts: getEnvList("OCI_ALLOWED_HOSTS"),
+
+		// Sync
+		SyncConcurrency: getEnvInt("SYNC_CONCURRENCY", 4),
+		SyncWorkers:     getEnvInt("SYNC_WORKERS", 2),
+
+		// Worker
+		SyncScheduleCron: getEnv("SYNC_SCHEDULE_CRON", "* * * * *"),
+		CleanupCron:      getEnv("CLEANUP_CRON", "0 0 * * *"),
+		SyncTimeout:      getEnvDuration("SYNC_TIMEOUT", 5*time.Minute),
+		SyncMinInterval:  getEnvDuration("SYNC_MIN_INTERVAL", time.Hour),
+		SyncJobDelay:     getEnvDuration("SYNC_JOB_DELAY", 2*time.Second),
+
+		// Shutdown
+		ShutdownGracePeriod: getEnvDuration("SHUTDOWN_GRACE_PERIOD", 30*time.Second),
+
+		// Operators
+		AdminToken: getEnv("ADMIN_TOKEN", ""),
 	}
 
 	// Validate required config
@@ -72,6 +130,37 @@ func Load() {
 		log.Println("Warning: GitHub OAuth credentials not configured")
 	}
 
+	if AppConfig.RegistryMaxPages < 1 {
+		log.Fatalf("FATAL: REGISTRY_MAX_PAGES must be at least 1, got %d", AppConfig.RegistryMaxPages)
+	}
+	if AppConfig.DockerHubMaxRetries < 0 {
+		log.Fatalf("FATAL: DOCKER_HUB_MAX_RETRIES must not be negative, got %d", AppConfig.DockerHubMaxRetries)
+	}
+	if AppConfig.SyncConcurrency < 1 {
+		log.Fatalf("FATAL: SYNC_CONCURRENCY must be at least 1, got %d", AppConfig.SyncConcurrency)
+	}
+	if AppConfig.SyncWorkers < 1 {
+		log.Fatalf("FATAL: SYNC_WORKERS must be at least 1, got %d", AppConfig.SyncWorkers)
+	}
+	if _, err := cron.ParseStandard(AppConfig.SyncScheduleCron); err != nil {
+		log.Fatalf("FATAL: SYNC_SCHEDULE_CRON is not a valid cron expression: %v", err)
+	}
+	if _, err := cron.ParseStandard(AppConfig.CleanupCron); err != nil {
+		log.Fatalf("FATAL: CLEANUP_CRON is not a valid cron expression: %v", err)
+	}
+	if AppConfig.SyncTimeout < time.Minute {
+		log.Fatalf("FATAL: SYNC_TIMEOUT must be at least 1m, got %s", AppConfig.SyncTimeout)
+	}
+	if AppConfig.SyncMinInterval < time.Minute || AppConfig.SyncMinInterval > 7*24*time.Hour {
+		log.Fatalf("FATAL: SYNC_MIN_INTERVAL must be between 1m and 168h, got %s", AppConfig.SyncMinInterval)
+	}
+	if AppConfig.SyncJobDelay < 0 {
+		log.Fatalf("FATAL: SYNC_JOB_DELAY must not be negative, got %s", AppConfig.SyncJobDelay)
+	}
+	if AppConfig.ShutdownGracePeriod < 0 {
+		log.Fatalf("FATAL: SHUTDOWN_GRACE_PERIOD must not be negative, got %s", AppConfig.ShutdownGracePeriod)
+	}
+
 	// Security: Validate critical secrets in production
 	if AppConfig.Environment == "production" {
 		if AppConfig.JWTSecret == "your-super-secret-jwt-key-change-in-production" {
@@ -92,3 +181,40 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvInt parses integer settings; a malformed value stops startup
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	intVal, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("FATAL: %s must be an integer, got %q", key, value)
+	}
+	return intVal
+}
+
+// getEnvDuration parses durations such as "90s" or "5m"; a malformed value stops startup
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("FATAL: %s must be a duration such as 30s or 5m, got %q", key, value)
+	}
+	return duration
+}
+
+// getEnvList splits a comma separated variable, dropping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -55,11 +55,54 @@ func Migrate() error {
 		}
 	}
 
-	return DB.AutoMigrate(
+	if err := backfillActivityEventKeys(); err != nil {
+		return err
+	}
+	if err := removeLegacyRepositoryPushes(); err != nil {
+		return err
+	}
+	if err := separatePullDays(); err != nil {
+		return err
+	}
+
+	// Tag revision identity now includes the namespace
+	if DB.Migrator().HasIndex(&models.TagRevision{}, "idx_tag_revision_identity") {
+		if err := DB.Migrator().DropIndex(&models.TagRevision{}, "idx_tag_revision_identity"); err != nil {
+			return fmt.Errorf("failed to drop old tag revision index: %w", err)
+		}
+	}
+
+	if err := scopeAccountIdentity(); err != nil {
+		return err
+	}
+
+	if err := DB.AutoMigrate(
 		&models.User{},
 		&models.DockerAccount{},
 		&models.ActivityEvent{},
-	)
+		&models.RepositoryPullSnapshot{},
+		&models.TagRevision{},
+		&models.DockerNamespace{},
+		&models.RepositoryCursor{},
+		&models.SyncRun{},
+		&models.SyncRunError{},
+		&models.SyncJob{},
+	); err != nil {
+		return err
+	}
+
+	// The sync lease replaced the sync_in_progress flag, which stuck after a crash
+	if DB.Migrator().HasColumn(&models.DockerAccount{}, "sync_in_progress") {
+		if err := DB.Migrator().DropColumn(&models.DockerAccount{}, "sync_in_progress"); err != nil {
+			return fmt.Errorf("failed to drop sync_in_progress column: %w", err)
+		}
+	}
+
+	if err := backfillNextSyncAt(); err != nil {
+		return err
+	}
+
+	return backfillNamespaces()
 }
 
 // fixSchemaIfNeeded checks for column naming issues and fixes them
@@ -117,6 +160,131 @@ func fixSchemaIfNeeded() error {
 	return nil
 }
 
+// backfillActivityEventKeys gives events created before event keys existed a unique
+// placeholder key so the unique index can be built. Their counts were inflated by
+// every resync, so pushes are reset to a single occurrence.
+func backfillActivityEventKeys() error {
+	if !DB.Migrator().HasTable(&models.ActivityEvent{}) || DB.Migrator().HasColumn(&models.ActivityEvent{}, "event_key") {
+		return nil
+	}
+
+	log.Println("Backfilling activity event keys...")
+
+	statements := []string{
+		`ALTER TABLE activity_events ADD COLUMN event_key text`,
+		`UPDATE activity_events SET event_key = 'legacy:' || id`,
+		`UPDATE activity_events SET count = 1 WHERE event_type = 'push'`,
+		`ALTER TABLE activity_events ALTER COLUMN event_key SET NOT NULL`,
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to backfill event keys: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// removeLegacyRepositoryPushes deletes the repository-level pushes (no tag) that syncs recorded
+// from last_updated before event keys existed. The tag pushes of the same day already count
+// them, so keeping both doubled every historical push day. Only legacy rows are touched, which
+// keeps this safe to run on every start.
+func removeLegacyRepositoryPushes() error {
+	if !DB.Migrator().HasTable(&models.ActivityEvent{}) {
+		return nil
+	}
+
+	result := DB.Exec(`
+		DELETE FROM activity_events legacy
+		WHERE legacy.event_type = 'push' AND COALESCE(legacy.tag, '') = '' AND legacy.event_key LIKE 'legacy:%'
+		AND EXISTS (
+			SELECT 1 FROM activity_events tagged
+			WHERE tagged.docker_account_id = legacy.docker_account_id
+			AND tagged.event_date = legacy.event_date
+			AND tagged.repository = legacy.repository
+			AND tagged.event_type = 'push' AND tagged.tag <> ''
+			AND tagged.deleted_at IS NULL
+		)
+	`)
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove legacy repository pushes: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Removed %d legacy repository-level pushes counted twice", result.RowsAffected)
+	}
+	return nil
+}
+
+// scopeAccountIdentity replaces the global docker_username unique index: usernames are only
+// unique within a registry, so the identity index also covers the provider and registry URL
+func scopeAccountIdentity() error {
+	if !DB.Migrator().HasTable(&models.DockerAccount{}) {
+		return nil
+	}
+
+	if DB.Migrator().HasIndex(&models.DockerAccount{}, "idx_docker_accounts_docker_username") {
+		if err := DB.Migrator().DropIndex(&models.DockerAccount{}, "idx_docker_accounts_docker_username"); err != nil {
+			return fmt.Errorf("failed to drop old docker username index: %w", err)
+		}
+	}
+
+	// Rows from before self-hosted registries have no registry URL; NULLs would never collide
+	if DB.Migrator().HasColumn(&models.DockerAccount{}, "registry_url") {
+		if err := DB.Exec(`UPDATE docker_accounts SET registry_url = '' WHERE registry_url IS NULL`).Error; err != nil {
+			return fmt.Errorf("failed to backfill registry URLs: %w", err)
+		}
+	}
+	return nil
+}
+
+// separatePullDays moves the per-tag pull-day markers, once stored as pull events, to their own
+// type so they stop adding to the pull_count deltas. Only pull-day markers carry a tag.
+func separatePullDays() error {
+	if !DB.Migrator().HasTable(&models.ActivityEvent{}) {
+		return nil
+	}
+
+	result := DB.Exec(`UPDATE activity_events SET event_type = ? WHERE event_type = ? AND COALESCE(tag, '') <> ''`, models.EventTypePullDay, models.EventTypePull)
+	if result.Error != nil {
+		return fmt.Errorf("failed to separate pull days: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Moved %d pull-day markers out of the pull totals", result.RowsAffected)
+	}
+	return nil
+}
+
+// backfillNamespaces assigns rows recorded before namespaces were tracked to the
+// account's own namespace
+func backfillNamespaces() error {
+	for _, table := range []string{"activity_events", "repository_pull_snapshots", "tag_revisions"} {
+		err := DB.Exec(fmt.Sprintf(`
+			UPDATE %s t SET namespace = a.docker_username
+			FROM docker_accounts a
+			WHERE t.docker_account_id = a.id AND t.namespace = ''
+		`, table)).Error
+		if err != nil {
+			return fmt.Errorf("failed to backfill namespaces in %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// backfillNextSyncAt schedules accounts synced before adaptive scheduling from their
+// preferred interval, so they do not all come due at once
+func backfillNextSyncAt() error {
+	err := DB.Exec(`
+		UPDATE docker_accounts SET next_sync_at = last_sync_at + sync_interval_hours * INTERVAL '1 hour'
+		WHERE next_sync_at IS NULL AND last_sync_at IS NOT NULL
+	`).Error
+	if err != nil {
+		return fmt.Errorf("failed to backfill next sync times: %w", err)
+	}
+	return nil
+}
+
 func Close() error {
 	sqlDB, err := DB.DB()
 	if err != nil {
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"docker-heatmap/internal/config"
@@ -24,9 +25,16 @@ func NewAuthHandler() *AuthHandler {
 	}
 }
 
+// oauthState is a pending OAuth flow. A non-zero packagesUserID marks a
+// read:packages scope upgrade started by that signed-in user.
+type oauthState struct {
+	expiresAt      time.Time
+	packagesUserID uint
+}
+
 // OAuthState stores temporary state for OAuth flow
 var (
-	oauthStates = make(map[string]time.Time)
+	oauthStates = make(map[string]oauthState)
 	stateMutex  sync.Mutex
 )
 
@@ -41,7 +49,7 @@ func (h *AuthHandler) InitiateGitHubAuth(c *fiber.Ctx) error {
 
 	// Store state with expiry
 	stateMutex.Lock()
-	oauthStates[state] = time.Now().Add(10 * time.Minute)
+	oauthStates[state] = oauthState{expiresAt: time.Now().Add(10 * time.Minute)}
 	stateMutex.Unlock()
 
 	// Clean old states
@@ -54,6 +62,53 @@ func (h *AuthHandler) InitiateGitHubAuth(c *fiber.Ctx) error {
 	})
 }
 
+// InitiateGitHubPackagesAuth starts an OAuth flow that grants read:packages for ghcr.io sync
+func (h *AuthHandler) InitiateGitHubPackagesAuth(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	state, err := utils.GenerateStateToken()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate state",
+		})
+	}
+
+	stateMutex.Lock()
+	oauthStates[state] = oauthState{expiresAt: time.Now().Add(10 * time.Minute), packagesUserID: user.ID}
+	stateMutex.Unlock()
+
+	go cleanupOAuthStates()
+
+	return c.JSON(fiber.Map{
+		"auth_url": h.authService.GetPackagesAuthURL(state),
+	})
+}
+
+// DisableGitHubPackages stops syncing ghcr.io packages and discards the stored token
+func (h *AuthHandler) DisableGitHubPackages(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	if err := h.authService.DisablePackages(user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to disable GitHub packages",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "GitHub packages sync disabled",
+	})
+}
+
 // GitHubCallback handles the OAuth callback
 func (h *AuthHandler) GitHubCallback(c *fiber.Ctx) error {
 	code := c.Query("code")
@@ -65,13 +120,13 @@ func (h *AuthHandler) GitHubCallback(c *fiber.Ctx) error {
 
 	// Validate state
 	stateMutex.Lock()
-	expiry, exists := oauthStates[state]
+	pending, exists := oauthStates[state]
 	if exists {
 		delete(oauthStates, state)
 	}
 	stateMutex.Unlock()
 
-	if !exists || time.Now().After(expiry) {
+	if !exists || time.Now().After(pending.expiresAt) {
 		return c.Redirect(config.AppConfig.FrontendURL + "/auth/error?message=invalid_state")
 	}
 
@@ -79,6 +134,21 @@ func (h *AuthHandler) GitHubCallback(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// Scope upgrade: store the packages token and send the user back to the dashboard
+	if pending.packagesUserID != 0 {
+		if err := h.authService.EnablePackages(ctx, pending.packagesUserID, code); err != nil {
+			message := "packages_failed"
+			switch {
+			case errors.Is(err, services.ErrPackagesScopeMissing):
+				message = "packages_scope_missing"
+			case errors.Is(err, services.ErrGitHubUserMismatch):
+				message = "packages_user_mismatch"
+			}
+			return c.Redirect(config.AppConfig.FrontendURL + "/auth/error?message=" + message)
+		}
+		return c.Redirect(config.AppConfig.FrontendURL + "/dashboard?ghcr=enabled")
+	}
+
 	user, err := h.authService.ExchangeCode(ctx, code)
 	if err != nil {
 		return c.Redirect(config.AppConfig.FrontendURL + "/auth/error?message=auth_failed")
@@ -122,8 +192,8 @@ func cleanupOAuthStates() {
 	defer stateMutex.Unlock()
 
 	now := time.Now()
-	for state, expiry := range oauthStates {
-		if now.After(expiry) {
+	for state, pending := range oauthStates {
+		if now.After(pending.expiresAt) {
 			delete(oauthStates, state)
 		}
 	}
@@ -2,15 +2,25 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"log"
 	"regexp"
+	"strconv"
 	"time"
 
 	"docker-heatmap/internal/middleware"
+	"docker-heatmap/internal/models"
 	"docker-heatmap/internal/services"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// Docker Hub repository names: lowercase alphanumerics separated by single ., _ or -
+var dockerRepoNameRegex = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*$`)
+
+// OCI repository names: path components per the distribution spec, separated by /
+var ociRepoNameRegex = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+
 // Docker username validation: 4-30 chars, alphanumeric with allowed special chars
 var dockerUsernameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{2,29}$`)
 
@@ -24,7 +34,17 @@ func NewDockerHandler() *DockerHandler {
 	}
 }
 
+type AddNamespaceRequest struct {
+	Namespace string `json:"namespace"`
+}
+
+type UpdateTokenRequest struct {
+	AccessToken string `json:"access_token"`
+}
+
 type ConnectDockerRequest struct {
+	Provider       string `json:"provider"`     // Registry provider, defaults to Docker Hub
+	RegistryURL    string `json:"registry_url"` // Required for self-hosted (oci) registries
 	DockerUsername string `json:"docker_username"`
 	AccessToken    string `json:"access_token"`
 }
@@ -51,40 +71,74 @@ func (h *DockerHandler) ConnectDocker(c *fiber.Ctx) error {
 		})
 	}
 
-	// Security: Validate username format
-	if !dockerUsernameRegex.MatchString(req.DockerUsername) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid Docker username format",
-		})
-	}
-
-	// Security: Validate token length (Docker PATs are typically 36+ chars)
-	if len(req.AccessToken) < 10 || len(req.AccessToken) > 500 {
+	provider, err := services.GetProvider(req.Provider)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid access token length",
+			"error": "Unsupported registry provider",
 		})
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	account, err := h.dockerService.ConnectAccount(ctx, user.ID, req.DockerUsername, req.AccessToken)
+	account, err := h.dockerService.ConnectAccount(ctx, user.ID, provider.Name(), req.RegistryURL, req.DockerUsername, req.AccessToken)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return credentialError(c, err)
 	}
 
 	return c.JSON(fiber.Map{
 		"message": "Docker account connected successfully",
 		"account": fiber.Map{
 			"id":              account.ID,
+			"provider":        account.Provider,
+			"registry_url":    account.RegistryURL,
 			"docker_username": account.DockerUsername,
 			"is_active":       account.IsActive,
 		},
 	})
 }
 
+// UpdateDockerToken replaces the access token of the connected account without touching its history
+func (h *DockerHandler) UpdateDockerToken(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req UpdateTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.AccessToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Access token is required",
+		})
+	}
+
+	account, err := h.dockerService.GetDockerAccount(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No Docker account connected",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := h.dockerService.UpdateToken(ctx, account, req.AccessToken); err != nil {
+		return credentialError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Access token updated successfully",
+	})
+}
+
 // GetDockerAccount returns the user's connected Docker account
 func (h *DockerHandler) GetDockerAccount(c *fiber.Ctx) error {
 	user := middleware.GetUserFromContext(c)
@@ -103,13 +157,71 @@ func (h *DockerHandler) GetDockerAccount(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{
 		"account": fiber.Map{
-			"id":               account.ID,
-			"docker_username":  account.DockerUsername,
-			"is_active":        account.IsActive,
-			"auto_refresh":     account.AutoRefresh,
-			"last_sync_at":     account.LastSyncAt,
-			"last_sync_error":  account.LastSyncError,
-			"sync_in_progress": account.SyncInProgress,
+			"id":                  account.ID,
+			"provider":            account.Provider,
+			"registry_url":        account.RegistryURL,
+			"token_scopes":        account.TokenScopes,
+			"owner_verified":      account.OwnerVerified,
+			"docker_username":     account.DockerUsername,
+			"is_active":           account.IsActive,
+			"auto_refresh":        account.AutoRefresh,
+			"sync_interval_hours": account.SyncIntervalHours,
+			"default_theme":       account.DefaultTheme,
+			"public_heatmap":      account.PublicHeatmap,
+			"last_sync_at":        account.LastSyncAt,
+			"last_sync_error":     account.LastSyncError,
+			"next_sync_at":        account.NextSyncAt,
+			"sync_in_progress":    account.SyncInProgress(),
+			"sync_truncated":      account.SyncTruncated,
+			"webhook_enabled":     account.WebhookSecretHash != "",
+		},
+	})
+}
+
+// UpdateDockerAccount changes sync and visibility settings of the connected account
+func (h *DockerHandler) UpdateDockerAccount(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req services.AccountSettings
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	account, err := h.dockerService.GetDockerAccount(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No Docker account connected",
+		})
+	}
+
+	if err := h.dockerService.UpdateAccountSettings(account, req); err != nil {
+		if errors.Is(err, services.ErrInvalidSettings) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update account settings",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Account settings updated",
+		"account": fiber.Map{
+			"id":                  account.ID,
+			"is_active":           account.IsActive,
+			"auto_refresh":        account.AutoRefresh,
+			"sync_interval_hours": account.SyncIntervalHours,
+			"next_sync_at":        account.NextSyncAt,
+			"default_theme":       account.DefaultTheme,
+			"public_heatmap":      account.PublicHeatmap,
 		},
 	})
 }
@@ -157,16 +269,330 @@ func (h *DockerHandler) SyncDockerActivity(c *fiber.Ctx) error {
 		})
 	}
 
-	if account.SyncInProgress {
+	if !account.IsActive {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Account is paused",
+		})
+	}
+
+	if account.SyncInProgress() {
 		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 			"error": "Sync already in progress",
 		})
 	}
 
-	// Trigger sync in background
-	go h.dockerService.SyncActivity(context.Background(), account.ID)
+	// Manual syncs jump the queue; a running one can be stopped through CancelDockerSync
+	job, err := h.dockerService.EnqueueSync(account.ID, models.SyncTriggerManual)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to queue sync",
+		})
+	}
 
 	return c.JSON(fiber.Map{
-		"message": "Sync started",
+		"message": "Sync queued",
+		"job_id":  job.ID,
+	})
+}
+
+// CancelDockerSync stops the account's in-progress sync
+func (h *DockerHandler) CancelDockerSync(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	account, err := h.dockerService.GetDockerAccount(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No Docker account connected",
+		})
+	}
+
+	if err := h.dockerService.CancelSync(account.ID); err != nil {
+		if errors.Is(err, services.ErrNoSyncRunning) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "No sync in progress",
+			})
+		}
+		log.Printf("Failed to cancel sync for account %d: %v", account.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to cancel sync",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Sync cancellation requested",
+	})
+}
+
+// GetSyncRuns returns the account's recent sync runs with per-repository errors
+// Query params:
+//   - limit: maximum number of runs (1-100, default 20)
+func (h *DockerHandler) GetSyncRuns(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	limit := 20
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	account, err := h.dockerService.GetDockerAccount(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No Docker account connected",
+		})
+	}
+
+	runs, err := h.dockerService.GetSyncRuns(account.ID, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch sync history",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"syncs": runs,
+	})
+}
+
+// RotateWebhookSecret generates (or replaces) the account's Docker Hub webhook secret
+func (h *DockerHandler) RotateWebhookSecret(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	account, err := h.dockerService.GetDockerAccount(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No Docker account connected",
+		})
+	}
+
+	secret, err := h.dockerService.RotateWebhookSecret(account)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate webhook secret",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":     "Webhook secret generated. It will not be shown again.",
+		"webhook_url": c.BaseURL() + "/api/webhooks/dockerhub/" + secret,
+	})
+}
+
+// GetTagHistory returns the tag/digest timeline of one of the user's repositories
+// Query params:
+//   - repository: repository path, for nested OCI paths the :repo segment cannot carry
+//   - namespace: namespace of the repository (default: the account's own)
+//   - tag: only return revisions of this tag
+//   - limit: maximum number of revisions (1-500, default 100)
+func (h *DockerHandler) GetTagHistory(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	repo := c.Params("repo", c.Query("repository"))
+
+	limit := 100
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 500 {
+			limit = parsed
+		}
+	}
+
+	account, err := h.dockerService.GetDockerAccount(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No Docker account connected",
+		})
+	}
+
+	if !validRepositoryName(account.Provider, repo) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid repository name",
+		})
+	}
+
+	namespace := c.Query("namespace", account.DockerUsername)
+
+	revisions, err := h.dockerService.GetTagRevisions(account.ID, namespace, repo, c.Query("tag"), limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch tag history",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"namespace":  namespace,
+		"repository": repo,
+		"revisions":  revisions,
+	})
+}
+
+// GetNamespaces lists the organization namespaces synced in addition to the account's own
+func (h *DockerHandler) GetNamespaces(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	account, err := h.dockerService.GetDockerAccount(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No Docker account connected",
+		})
+	}
+
+	namespaces, err := h.dockerService.GetNamespaces(account.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch namespaces",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"own_namespace": account.DockerUsername,
+		"namespaces":    namespaces,
+	})
+}
+
+// AddNamespace adds an organization namespace to sync
+func (h *DockerHandler) AddNamespace(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req AddNamespaceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if !dockerUsernameRegex.MatchString(req.Namespace) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid namespace format",
+		})
+	}
+
+	account, err := h.dockerService.GetDockerAccount(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No Docker account connected",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	namespace, err := h.dockerService.AddNamespace(ctx, account, req.Namespace)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNamespaceExists):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrNamespaceNotAccessible), errors.Is(err, services.ErrInvalidDockerToken):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":   "Namespace added. It will be included from the next sync.",
+		"namespace": namespace,
+	})
+}
+
+// RemoveNamespace stops syncing an organization namespace
+func (h *DockerHandler) RemoveNamespace(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	account, err := h.dockerService.GetDockerAccount(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No Docker account connected",
+		})
+	}
+
+	if err := h.dockerService.RemoveNamespace(account.ID, c.Params("namespace")); err != nil {
+		if errors.Is(err, services.ErrNamespaceNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Namespace not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to remove namespace",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Namespace removed",
+	})
+}
+
+// validRepositoryName checks a repository name against the naming rules of the account's registry
+func validRepositoryName(provider, repo string) bool {
+	if provider == services.ProviderOCI {
+		return len(repo) <= 255 && ociRepoNameRegex.MatchString(repo)
+	}
+	return dockerRepoNameRegex.MatchString(repo)
+}
+
+// credentialError answers a failed credential check. Only known outcomes are described; anything
+// else stays in the log, since registry URLs are user supplied and raw errors would reveal what
+// the server can reach.
+func credentialError(c *fiber.Ctx, err error) error {
+	status, message := fiber.StatusBadGateway, "Could not verify the registry credentials"
+
+	switch {
+	case errors.Is(err, services.ErrTokenOwnerMismatch):
+		status, message = fiber.StatusForbidden, "Access token does not belong to this username"
+	case errors.Is(err, services.ErrDockerUsernameTaken):
+		status, message = fiber.StatusConflict, err.Error()
+	case errors.Is(err, services.ErrInvalidCredentialFormat), errors.Is(err, services.ErrInvalidRegistryURL), errors.Is(err, services.ErrRegistryHostNotAllowed):
+		status, message = fiber.StatusBadRequest, err.Error()
+	case errors.Is(err, services.ErrRegistryUserNotFound):
+		status, message = fiber.StatusBadRequest, "Registry user not found"
+	case errors.Is(err, services.ErrInvalidDockerToken), errors.Is(err, services.ErrDockerHubAuthFailed),
+		errors.Is(err, services.ErrRegistryAuthFailed), errors.Is(err, services.ErrGitHubPackagesAuthFailed):
+		status, message = fiber.StatusBadRequest, "The registry rejected these credentials"
+	default:
+		log.Printf("Registry credential check failed: %v", err)
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"error": message,
 	})
 }
@@ -25,13 +25,15 @@ func NewHeatmapHandler() *HeatmapHandler {
 // GetHeatmapSVG returns the heatmap as an SVG image with customization options
 // Query params:
 //   - days: number of days (1-365, default 365)
-//   - theme: color theme (github, docker, dracula, nord, etc.) or "custom"
+//   - theme: color theme (github, docker, dracula, nord, etc.) or "custom" (default: the account's default theme)
 //   - cell_size: size of each cell (5-20, default 11)
 //   - radius: border radius of cells (0-10, default 2)
 //   - hide_legend: hide the color legend (true/false)
 //   - hide_total: hide the total count (true/false)
 //   - hide_labels: hide month/day labels (true/false)
 //   - title: custom title text
+//   - orgs: include organization namespaces (true/false)
+//   - namespace: only show activity from this namespace
 //   - bg_color: custom background color (hex without #)
 //   - text_color: custom text color (hex without #)
 //   - color0-color4: custom level colors (hex without #)
@@ -49,7 +51,7 @@ func (h *HeatmapHandler) GetHeatmapSVG(c *fiber.Ctx) error {
 
 	// Parse options from query params
 	opts := services.SVGOptions{
-		Theme:       c.Query("theme", "github"),
+		Theme:       c.Query("theme"),
 		Days:        365,
 		CellSize:    11,
 		CellRadius:  2,
@@ -57,6 +59,8 @@ func (h *HeatmapHandler) GetHeatmapSVG(c *fiber.Ctx) error {
 		HideTotal:   c.Query("hide_total") == "true" || c.Query("hide_total") == "1",
 		HideLabels:  c.Query("hide_labels") == "true" || c.Query("hide_labels") == "1",
 		CustomTitle: c.Query("title"),
+		IncludeOrgs: c.Query("orgs") == "true" || c.Query("orgs") == "1",
+		Namespace:   c.Query("namespace"),
 	}
 
 	// Parse numeric options with validation
@@ -105,6 +109,11 @@ func (h *HeatmapHandler) GetHeatmapSVG(c *fiber.Ctx) error {
 				"error": "User not found or no Docker account connected",
 			})
 		}
+		if err == services.ErrHeatmapPrivate {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Heatmap is private",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate heatmap",
 		})
@@ -170,6 +179,10 @@ func (h *HeatmapHandler) GetAvailableThemes(c *fiber.Ctx) error {
 }
 
 // GetActivityJSON returns activity data as JSON
+// Query params:
+//   - days: number of days (1-365, default 365)
+//   - orgs: include organization namespaces and break totals out per namespace (true/false)
+//   - namespace: only return activity from this namespace
 func (h *HeatmapHandler) GetActivityJSON(c *fiber.Ctx) error {
 	username := c.Params("username")
 
@@ -190,13 +203,23 @@ func (h *HeatmapHandler) GetActivityJSON(c *fiber.Ctx) error {
 		}
 	}
 
-	activities, err := h.dockerService.GetActivitySummary(username, days)
+	filter := services.ActivityFilter{
+		IncludeOrgs: c.Query("orgs") == "true" || c.Query("orgs") == "1",
+		Namespace:   c.Query("namespace"),
+	}
+
+	activities, err := h.dockerService.GetActivitySummary(username, days, filter)
 	if err != nil {
 		if err == services.ErrDockerAccountNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "User not found or no Docker account connected",
 			})
 		}
+		if err == services.ErrHeatmapPrivate {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Heatmap is private",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch activity",
 		})
@@ -211,8 +234,7 @@ func (h *HeatmapHandler) GetActivityJSON(c *fiber.Ctx) error {
 		totalBuilds += a.Builds
 	}
 
-	c.Set("Cache-Control", "public, max-age=3600") // Cache for 1 hour
-	return c.JSON(fiber.Map{
+	response := fiber.Map{
 		"username": username,
 		"days":     days,
 		"totals": fiber.Map{
@@ -222,7 +244,17 @@ func (h *HeatmapHandler) GetActivityJSON(c *fiber.Ctx) error {
 			"builds":     totalBuilds,
 		},
 		"activity": activities,
-	})
+	}
+
+	// Break totals out per namespace when organization activity is included
+	if filter.IncludeOrgs {
+		if byNamespace, err := h.dockerService.GetNamespaceTotals(username, days); err == nil {
+			response["by_namespace"] = byNamespace
+		}
+	}
+
+	c.Set("Cache-Control", "public, max-age=3600") // Cache for 1 hour
+	return c.JSON(response)
 }
 
 // GetProfilePage returns profile data for public profile page
@@ -257,8 +289,8 @@ func (h *HeatmapHandler) GetProfilePage(c *fiber.Ctx) error {
 		})
 	}
 
-	// Get activity summary
-	activities, _ := h.dockerService.GetActivitySummary(username, 365)
+	// Get activity summary (empty when the heatmap is private)
+	activities, _ := h.dockerService.GetActivitySummary(username, 365, services.ActivityFilter{})
 
 	var totalActivities int
 	for _, a := range activities {
@@ -273,8 +305,10 @@ func (h *HeatmapHandler) GetProfilePage(c *fiber.Ctx) error {
 			"bio":             user.Bio,
 		},
 		"docker": fiber.Map{
-			"username":     account.DockerUsername,
-			"last_sync_at": account.LastSyncAt,
+			"username":       account.DockerUsername,
+			"last_sync_at":   account.LastSyncAt,
+			"public_heatmap": account.PublicHeatmap,
+			"default_theme":  account.DefaultTheme,
 		},
 		"stats": fiber.Map{
 			"total_activities": totalActivities,
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"docker-heatmap/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WebhookHandler struct {
+	dockerService *services.DockerHubService
+}
+
+func NewWebhookHandler() *WebhookHandler {
+	return &WebhookHandler{
+		dockerService: services.NewDockerHubService(),
+	}
+}
+
+// ReceiveDockerHub accepts Docker Hub push webhooks and records the push immediately
+func (h *WebhookHandler) ReceiveDockerHub(c *fiber.Ctx) error {
+	var payload services.DockerHubWebhookPayload
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := h.dockerService.HandlePushWebhook(ctx, c.Params("secret"), &payload); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidWebhookSecret):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Unknown webhook",
+			})
+		case errors.Is(err, services.ErrInvalidWebhookPayload):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record push",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Push recorded",
+	})
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"docker-heatmap/internal/worker"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WorkerHandler struct {
+	syncWorker *worker.SyncWorker
+}
+
+func NewWorkerHandler(syncWorker *worker.SyncWorker) *WorkerHandler {
+	return &WorkerHandler{
+		syncWorker: syncWorker,
+	}
+}
+
+// GetStatus reports the effective worker settings of the replica serving the request and the job queue
+func (h *WorkerHandler) GetStatus(c *fiber.Ctx) error {
+	status, err := h.syncWorker.Status()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load worker status",
+		})
+	}
+
+	return c.JSON(status)
+}
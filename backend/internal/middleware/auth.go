@@ -1,8 +1,10 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"strings"
 
+	"docker-heatmap/internal/config"
 	"docker-heatmap/internal/database"
 	"docker-heatmap/internal/models"
 	"docker-heatmap/internal/utils"
@@ -94,3 +96,25 @@ func GetUserFromContext(c *fiber.Ctx) *models.User {
 	}
 	return user
 }
+
+// AdminTokenMiddleware admits operators presenting ADMIN_TOKEN in the X-Admin-Token header, on
+// top of their user session. Without a configured token the routes behind it do not exist.
+func AdminTokenMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adminToken := config.AppConfig.AdminToken
+		if adminToken == "" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Not found",
+			})
+		}
+
+		token := c.Get("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid admin token",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -12,6 +12,10 @@ const (
 	EventTypePush  EventType = "push"
 	EventTypePull  EventType = "pull"
 	EventTypeBuild EventType = "build"
+
+	// EventTypePullDay marks a day a tag was last pulled. It carries no pull count, so it only
+	// feeds the sync schedule and never the activity totals.
+	EventTypePullDay EventType = "pull_day"
 )
 
 type ActivityEvent struct {
@@ -21,7 +25,7 @@ type ActivityEvent struct {
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Foreign Key
-	DockerAccountID uint          `gorm:"column:docker_account_id;not null;index:idx_activity_account_date" json:"docker_account_id"`
+	DockerAccountID uint          `gorm:"column:docker_account_id;not null;index:idx_activity_account_date;uniqueIndex:idx_activity_account_event_key" json:"docker_account_id"`
 	DockerAccount   DockerAccount `gorm:"foreignKey:DockerAccountID" json:"-"`
 
 	// Event Data
@@ -29,9 +33,17 @@ type ActivityEvent struct {
 	EventDate time.Time `gorm:"column:event_date;not null;index:idx_activity_account_date" json:"event_date"`
 	Count     int       `gorm:"column:count;not null;default:1" json:"count"`
 
+	// EventKey identifies the underlying occurrence (e.g. repo + tag + digest + pushed-at)
+	// so that re-ingesting it never creates a second row
+	EventKey string `gorm:"column:event_key;not null;uniqueIndex:idx_activity_account_event_key" json:"-"`
+
 	// Repository Info
+	Namespace  string `gorm:"column:namespace;not null;default:'';index" json:"namespace,omitempty"`
 	Repository string `gorm:"column:repository" json:"repository,omitempty"`
 	Tag        string `gorm:"column:tag" json:"tag,omitempty"`
+
+	// Source is the registry the event was observed on (e.g. dockerhub, ghcr); empty for legacy rows
+	Source string `gorm:"column:source;not null;default:'';index" json:"source,omitempty"`
 }
 
 // TableName specifies the table name
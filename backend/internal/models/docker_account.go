@@ -16,21 +16,37 @@ type DockerAccount struct {
 	UserID uint `gorm:"column:user_id;not null;index" json:"user_id"`
 	User   User `gorm:"foreignKey:UserID" json:"-"`
 
-	// Docker Hub Data
-	DockerUsername string `gorm:"column:docker_username;not null;uniqueIndex" json:"docker_username"`
+	// Registry Data; a username is unique within its registry only
+	Provider       string `gorm:"column:provider;not null;default:'dockerhub';uniqueIndex:idx_docker_account_identity,priority:1" json:"provider"`
+	RegistryURL    string `gorm:"column:registry_url;not null;default:'';uniqueIndex:idx_docker_account_identity,priority:2" json:"registry_url,omitempty"` // Self-hosted registries only
+	DockerUsername string `gorm:"column:docker_username;not null;uniqueIndex:idx_docker_account_identity,priority:3" json:"docker_username"`
+	OwnerVerified  bool   `gorm:"column:owner_verified;not null;default:false" json:"owner_verified"` // The registry confirmed the token belongs to the username
 
 	// Encrypted Access Token (AES-256 encrypted)
 	EncryptedToken string `gorm:"column:encrypted_token;not null" json:"-"`
 	TokenIV        string `gorm:"column:token_iv;not null" json:"-"`
+	TokenScopes    string `gorm:"column:token_scopes" json:"token_scopes,omitempty"` // Space separated, as reported by the registry
+
+	// Webhook secret (SHA-256 hash; the secret itself is only shown once)
+	WebhookSecretHash string `gorm:"column:webhook_secret_hash;index" json:"-"`
 
 	// Sync Status
-	LastSyncAt     *time.Time `gorm:"column:last_sync_at" json:"last_sync_at,omitempty"`
-	LastSyncError  string     `gorm:"column:last_sync_error" json:"last_sync_error,omitempty"`
-	SyncInProgress bool       `gorm:"column:sync_in_progress;default:false" json:"sync_in_progress"`
+	LastSyncAt    *time.Time `gorm:"column:last_sync_at" json:"last_sync_at,omitempty"`
+	LastSyncError string     `gorm:"column:last_sync_error" json:"last_sync_error,omitempty"`
+	SyncTruncated bool       `gorm:"column:sync_truncated;default:false" json:"sync_truncated"` // Last sync hit the pagination ceiling
+	NextSyncAt    *time.Time `gorm:"column:next_sync_at;index" json:"next_sync_at,omitempty"`   // Scheduled from recent push and pull activity; null means due now
+
+	// Sync lease: the owner holding it may sync until it expires; heartbeats keep it alive
+	SyncLeaseOwner        string     `gorm:"column:sync_lease_owner" json:"-"`
+	SyncLeaseExpiresAt    *time.Time `gorm:"column:sync_lease_expires_at" json:"sync_lease_expires_at,omitempty"`
+	SyncCancelRequestedAt *time.Time `gorm:"column:sync_cancel_requested_at" json:"-"` // Set to stop the lease holder's sync, wherever it runs
 
 	// Settings
-	IsActive    bool `gorm:"column:is_active;default:true" json:"is_active"`
-	AutoRefresh bool `gorm:"column:auto_refresh;default:true" json:"auto_refresh"`
+	IsActive          bool   `gorm:"column:is_active;default:true" json:"is_active"`
+	AutoRefresh       bool   `gorm:"column:auto_refresh;default:true" json:"auto_refresh"`
+	SyncIntervalHours int    `gorm:"column:sync_interval_hours;not null;default:6" json:"sync_interval_hours"` // Preferred time between scheduled syncs
+	DefaultTheme      string `gorm:"column:default_theme;not null;default:'github'" json:"default_theme"`      // SVG theme when the embed URL names none
+	PublicHeatmap     bool   `gorm:"column:public_heatmap;default:true" json:"public_heatmap"`                 // Serve heatmap SVG/JSON to anyone
 
 	// Relationships
 	ActivityEvents []ActivityEvent `gorm:"foreignKey:DockerAccountID" json:"activity_events,omitempty"`
@@ -51,3 +67,8 @@ func (d *DockerAccount) BeforeUpdate(tx *gorm.DB) error {
 	d.UpdatedAt = time.Now()
 	return nil
 }
+
+// SyncInProgress reports whether a live sync lease is held on the account
+func (d *DockerAccount) SyncInProgress() bool {
+	return d.SyncLeaseExpiresAt != nil && time.Now().Before(*d.SyncLeaseExpiresAt)
+}
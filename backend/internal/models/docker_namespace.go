@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DockerNamespace is an additional namespace (usually an organization) synced for an account
+type DockerNamespace struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Foreign Key
+	DockerAccountID uint          `gorm:"column:docker_account_id;not null;uniqueIndex:idx_namespace_account_name" json:"-"`
+	DockerAccount   DockerAccount `gorm:"foreignKey:DockerAccountID" json:"-"`
+
+	Name string `gorm:"column:name;not null;uniqueIndex:idx_namespace_account_name" json:"name"`
+}
+
+// TableName specifies the table name
+func (DockerNamespace) TableName() string {
+	return "docker_namespaces"
+}
+
+func (n *DockerNamespace) BeforeCreate(tx *gorm.DB) error {
+	n.CreatedAt = time.Now()
+	return nil
+}
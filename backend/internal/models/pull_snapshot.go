@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// RepositoryPullSnapshot records a repository's cumulative Docker Hub pull_count at sync time.
+// Consecutive snapshots are diffed to produce pull activity.
+type RepositoryPullSnapshot struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Foreign Key
+	DockerAccountID uint          `gorm:"column:docker_account_id;not null;index:idx_pull_snapshot_account_repo" json:"docker_account_id"`
+	DockerAccount   DockerAccount `gorm:"foreignKey:DockerAccountID" json:"-"`
+
+	// Snapshot Data
+	Namespace  string    `gorm:"column:namespace;not null;default:''" json:"namespace"`
+	Repository string    `gorm:"column:repository;not null;index:idx_pull_snapshot_account_repo" json:"repository"`
+	PullCount  int64     `gorm:"column:pull_count;not null" json:"pull_count"`
+	CapturedAt time.Time `gorm:"column:captured_at;not null;index:idx_pull_snapshot_account_repo" json:"captured_at"`
+}
+
+// TableName specifies the table name
+func (RepositoryPullSnapshot) TableName() string {
+	return "repository_pull_snapshots"
+}
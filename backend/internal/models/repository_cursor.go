@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// RepositoryCursor is the per-repository high-water mark that lets syncs skip
+// repositories that have not changed since they were last scanned
+type RepositoryCursor struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Foreign Key
+	DockerAccountID uint          `gorm:"column:docker_account_id;not null;uniqueIndex:idx_repository_cursor_identity" json:"-"`
+	DockerAccount   DockerAccount `gorm:"foreignKey:DockerAccountID" json:"-"`
+
+	Namespace  string `gorm:"column:namespace;not null;uniqueIndex:idx_repository_cursor_identity" json:"namespace"`
+	Repository string `gorm:"column:repository;not null;uniqueIndex:idx_repository_cursor_identity" json:"repository"`
+
+	// LastUpdated is the registry's raw last_updated value at the last tag scan, or the provider's
+	// change marker for registries that report none
+	LastUpdated string `gorm:"column:last_updated" json:"last_updated"`
+	// LastTagPushAt is the newest tag push seen so far
+	LastTagPushAt *time.Time `gorm:"column:last_tag_push_at" json:"last_tag_push_at,omitempty"`
+	// FullScanAt is when every tag was last listed without stopping at the cursor
+	FullScanAt time.Time `gorm:"column:full_scan_at" json:"full_scan_at"`
+}
+
+// TableName specifies the table name
+func (RepositoryCursor) TableName() string {
+	return "repository_cursors"
+}
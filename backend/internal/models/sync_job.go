@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+type SyncJobStatus string
+
+const (
+	SyncJobStatusPending SyncJobStatus = "pending"
+	SyncJobStatusRunning SyncJobStatus = "running"
+	SyncJobStatusDone    SyncJobStatus = "done"
+	SyncJobStatusDead    SyncJobStatus = "dead" // Gave up after a permanent error or too many attempts
+)
+
+// Job priorities; higher runs first
+const (
+	SyncJobPriorityScheduled = 0
+	SyncJobPriorityWebhook   = 25
+	SyncJobPriorityConnect   = 50
+	SyncJobPriorityManual    = 100
+)
+
+// SyncJob is a queued sync of an account, claimed by worker replicas in priority order
+type SyncJob struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// Foreign Key; at most one pending job per account, later requests merge into it
+	DockerAccountID uint          `gorm:"column:docker_account_id;not null;uniqueIndex:idx_sync_job_pending_account,where:status = 'pending'" json:"-"`
+	DockerAccount   DockerAccount `gorm:"foreignKey:DockerAccountID" json:"-"`
+
+	Trigger  SyncTrigger   `gorm:"column:trigger;not null" json:"trigger"`
+	Priority int           `gorm:"column:priority;not null;default:0;index:idx_sync_job_queue,priority:2" json:"priority"`
+	Status   SyncJobStatus `gorm:"column:status;not null;default:'pending';index:idx_sync_job_queue,priority:1" json:"status"`
+	RunAt    time.Time     `gorm:"column:run_at;not null;index:idx_sync_job_queue,priority:3" json:"run_at"` // Earliest time the job may be claimed
+
+	// Retries
+	Attempts    int    `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	MaxAttempts int    `gorm:"column:max_attempts;not null;default:5" json:"max_attempts"`
+	LastError   string `gorm:"column:last_error" json:"last_error,omitempty"`
+
+	// Claim
+	LockedBy string     `gorm:"column:locked_by" json:"-"`
+	LockedAt *time.Time `gorm:"column:locked_at" json:"locked_at,omitempty"`
+
+	CreatedAt  time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	FinishedAt *time.Time `gorm:"column:finished_at" json:"finished_at,omitempty"`
+}
+
+// TableName specifies the table name
+func (SyncJob) TableName() string {
+	return "sync_jobs"
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+)
+
+type SyncTrigger string
+
+const (
+	SyncTriggerCron    SyncTrigger = "cron"
+	SyncTriggerManual  SyncTrigger = "manual"
+	SyncTriggerWebhook SyncTrigger = "webhook"
+	SyncTriggerConnect SyncTrigger = "connect"
+)
+
+type SyncStatus string
+
+const (
+	SyncStatusRunning     SyncStatus = "running"
+	SyncStatusSuccess     SyncStatus = "success"
+	SyncStatusPartial     SyncStatus = "partial" // Finished, but some repositories or namespaces failed
+	SyncStatusFailed      SyncStatus = "failed"
+	SyncStatusCancelled   SyncStatus = "cancelled"
+	SyncStatusInterrupted SyncStatus = "interrupted" // Stopped by a server shutdown or crash
+)
+
+// SyncRun records one sync of an account and what it found
+type SyncRun struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	StartedAt  time.Time  `gorm:"column:started_at;not null;index:idx_sync_run_account_started" json:"started_at"`
+	FinishedAt *time.Time `gorm:"column:finished_at" json:"finished_at,omitempty"`
+
+	// Foreign Key
+	DockerAccountID uint          `gorm:"column:docker_account_id;not null;index:idx_sync_run_account_started" json:"-"`
+	DockerAccount   DockerAccount `gorm:"foreignKey:DockerAccountID" json:"-"`
+
+	Trigger SyncTrigger `gorm:"column:trigger;not null" json:"trigger"`
+	Status  SyncStatus  `gorm:"column:status;not null" json:"status"`
+	Error   string      `gorm:"column:error" json:"error,omitempty"`
+
+	// Statistics
+	ReposScanned  int  `gorm:"column:repos_scanned;not null;default:0" json:"repos_scanned"`
+	TagsScanned   int  `gorm:"column:tags_scanned;not null;default:0" json:"tags_scanned"`
+	EventsCreated int  `gorm:"column:events_created;not null;default:0" json:"events_created"`
+	Truncated     bool `gorm:"column:truncated;not null;default:false" json:"truncated"`
+
+	// Relationships
+	Errors []SyncRunError `gorm:"foreignKey:SyncRunID;constraint:OnDelete:CASCADE" json:"errors,omitempty"`
+}
+
+// TableName specifies the table name
+func (SyncRun) TableName() string {
+	return "sync_runs"
+}
+
+// SyncRunError is a failure confined to one namespace or repository of a sync run
+type SyncRunError struct {
+	ID         uint   `gorm:"primaryKey" json:"-"`
+	SyncRunID  uint   `gorm:"column:sync_run_id;not null;index" json:"-"`
+	Namespace  string `gorm:"column:namespace;not null" json:"namespace"`
+	Repository string `gorm:"column:repository" json:"repository,omitempty"` // Empty when the whole namespace failed
+	Message    string `gorm:"column:message;not null" json:"message"`
+}
+
+// TableName specifies the table name
+func (SyncRunError) TableName() string {
+	return "sync_run_errors"
+}
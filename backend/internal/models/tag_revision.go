@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// TagRevision records a digest a tag pointed to and when it was pushed,
+// preserving history when a tag such as `latest` is re-pushed
+type TagRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"first_seen_at"`
+
+	// Foreign Key
+	DockerAccountID uint          `gorm:"column:docker_account_id;not null;uniqueIndex:idx_tag_revision_namespace_identity" json:"-"`
+	DockerAccount   DockerAccount `gorm:"foreignKey:DockerAccountID" json:"-"`
+
+	// Revision Data
+	Namespace  string    `gorm:"column:namespace;not null;default:'';uniqueIndex:idx_tag_revision_namespace_identity" json:"namespace"`
+	Repository string    `gorm:"column:repository;not null;uniqueIndex:idx_tag_revision_namespace_identity" json:"repository"`
+	Tag        string    `gorm:"column:tag;not null;uniqueIndex:idx_tag_revision_namespace_identity" json:"tag"`
+	Digest     string    `gorm:"column:digest;not null;uniqueIndex:idx_tag_revision_namespace_identity" json:"digest"`
+	PushedAt   time.Time `gorm:"column:pushed_at;not null;uniqueIndex:idx_tag_revision_namespace_identity" json:"pushed_at"`
+}
+
+// TableName specifies the table name
+func (TagRevision) TableName() string {
+	return "tag_revisions"
+}
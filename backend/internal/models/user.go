@@ -19,6 +19,12 @@ type User struct {
 	AvatarURL      string `gorm:"column:avatar_url" json:"avatar_url,omitempty"`
 	Name           string `gorm:"column:name" json:"name,omitempty"`
 
+	// GitHub Packages (ghcr.io) access, granted through an opt-in read:packages scope upgrade.
+	// The OAuth token is AES-256 encrypted like registry tokens.
+	GHCREnabled           bool   `gorm:"column:ghcr_enabled;default:false" json:"ghcr_enabled"`
+	GitHubPackagesToken   string `gorm:"column:github_packages_token" json:"-"`
+	GitHubPackagesTokenIV string `gorm:"column:github_packages_token_iv" json:"-"`
+
 	// Profile Settings
 	PublicProfile bool   `gorm:"column:public_profile;default:true" json:"public_profile"`
 	Bio           string `gorm:"column:bio" json:"bio,omitempty"`
@@ -5,6 +5,8 @@ import (
 	"docker-heatmap/internal/database"
 	"docker-heatmap/internal/handlers"
 	"docker-heatmap/internal/middleware"
+	"docker-heatmap/internal/services"
+	"docker-heatmap/internal/worker"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -12,7 +14,7 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 )
 
-func SetupRouter() *fiber.App {
+func SetupRouter(syncWorker *worker.SyncWorker) *fiber.App {
 	app := fiber.New(fiber.Config{
 		ErrorHandler: customErrorHandler,
 		AppName:      "Docker Heatmap API",
@@ -50,7 +52,7 @@ func SetupRouter() *fiber.App {
 
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     origins,
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
 		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-Requested-With",
 		AllowCredentials: true,
 	}))
@@ -73,9 +75,10 @@ func SetupRouter() *fiber.App {
 		}
 
 		return c.JSON(fiber.Map{
-			"status":   status,
-			"database": dbStatus,
-			"service":  "docker-heatmap-api",
+			"status":     status,
+			"database":   dbStatus,
+			"service":    "docker-heatmap-api",
+			"docker_hub": services.DockerHubQuota(),
 		})
 	})
 
@@ -88,6 +91,8 @@ func SetupRouter() *fiber.App {
 	dockerHandler := handlers.NewDockerHandler()
 	heatmapHandler := handlers.NewHeatmapHandler()
 	userHandler := handlers.NewUserHandler()
+	webhookHandler := handlers.NewWebhookHandler()
+	workerHandler := handlers.NewWorkerHandler(syncWorker)
 
 	// Public routes (with rate limiting)
 	public := api.Group("")
@@ -101,6 +106,9 @@ func SetupRouter() *fiber.App {
 	public.Get("/profile/:username", heatmapHandler.GetProfilePage)
 	public.Get("/themes", heatmapHandler.GetAvailableThemes)
 
+	// Inbound registry webhooks (authenticated by the per-account secret in the path)
+	public.Post("/webhooks/dockerhub/:secret", webhookHandler.ReceiveDockerHub)
+
 	// Auth routes (strict rate limiting)
 	auth := api.Group("/auth")
 	auth.Use(middleware.StrictRateLimitMiddleware())
@@ -117,12 +125,27 @@ func SetupRouter() *fiber.App {
 	protected.Put("/user/me", userHandler.UpdateProfile)
 	protected.Get("/user/embed", userHandler.GetEmbedCode)
 	protected.Post("/auth/logout", authHandler.Logout)
+	protected.Get("/auth/github/packages", authHandler.InitiateGitHubPackagesAuth)
+	protected.Delete("/auth/github/packages", authHandler.DisableGitHubPackages)
 
 	// Docker routes
 	protected.Post("/docker/connect", dockerHandler.ConnectDocker)
 	protected.Get("/docker/account", dockerHandler.GetDockerAccount)
+	protected.Patch("/docker/account", dockerHandler.UpdateDockerAccount)
+	protected.Put("/docker/token", dockerHandler.UpdateDockerToken)
 	protected.Delete("/docker/disconnect", dockerHandler.DisconnectDocker)
 	protected.Post("/docker/sync", dockerHandler.SyncDockerActivity)
+	protected.Delete("/docker/sync", dockerHandler.CancelDockerSync)
+	protected.Get("/docker/syncs", dockerHandler.GetSyncRuns)
+	protected.Post("/docker/webhook", dockerHandler.RotateWebhookSecret)
+	protected.Get("/docker/repositories/:repo/tags", dockerHandler.GetTagHistory)
+	protected.Get("/docker/tags", dockerHandler.GetTagHistory) // ?repository= for nested OCI paths
+	protected.Get("/docker/namespaces", dockerHandler.GetNamespaces)
+	protected.Post("/docker/namespaces", dockerHandler.AddNamespace)
+	protected.Delete("/docker/namespaces/:namespace", dockerHandler.RemoveNamespace)
+
+	// Worker routes (operators only)
+	protected.Get("/worker/status", middleware.AdminTokenMiddleware(), workerHandler.GetStatus)
 
 	return app
 }
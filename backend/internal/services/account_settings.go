@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"docker-heatmap/internal/database"
+	"docker-heatmap/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidSettings = errors.New("invalid account settings")
+	ErrHeatmapPrivate  = errors.New("heatmap is private")
+	ErrAccountPaused   = errors.New("account is paused")
+)
+
+// Bounds for DockerAccount.SyncIntervalHours
+const (
+	MinSyncIntervalHours = 1
+	MaxSyncIntervalHours = 7 * 24
+)
+
+// AccountSettings is a partial update of a Docker account's settings; nil fields are left unchanged
+type AccountSettings struct {
+	AutoRefresh       *bool   `json:"auto_refresh"`
+	IsActive          *bool   `json:"is_active"` // false pauses syncs and webhooks, true resumes them
+	SyncIntervalHours *int    `json:"sync_interval_hours"`
+	DefaultTheme      *string `json:"default_theme"`
+	PublicHeatmap     *bool   `json:"public_heatmap"`
+}
+
+// Validate checks every provided field
+func (a AccountSettings) Validate() error {
+	if a.SyncIntervalHours != nil && (*a.SyncIntervalHours < MinSyncIntervalHours || *a.SyncIntervalHours > MaxSyncIntervalHours) {
+		return fmt.Errorf("%w: sync_interval_hours must be between %d and %d", ErrInvalidSettings, MinSyncIntervalHours, MaxSyncIntervalHours)
+	}
+	if a.DefaultTheme != nil {
+		if _, ok := Themes[*a.DefaultTheme]; !ok {
+			return fmt.Errorf("%w: unknown theme %q", ErrInvalidSettings, *a.DefaultTheme)
+		}
+	}
+	return nil
+}
+
+// UpdateAccountSettings validates and applies a settings update. The worker reads these
+// columns on every run, so changes take effect from its next selection.
+func (s *DockerHubService) UpdateAccountSettings(account *models.DockerAccount, settings AccountSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	updates := make(map[string]interface{})
+	if settings.AutoRefresh != nil {
+		updates["auto_refresh"] = *settings.AutoRefresh
+	}
+	if settings.IsActive != nil {
+		updates["is_active"] = *settings.IsActive
+	}
+	if settings.SyncIntervalHours != nil {
+		updates["sync_interval_hours"] = *settings.SyncIntervalHours
+		// A shorter preference brings the next scheduled sync forward right away (LEAST skips nulls)
+		updates["next_sync_at"] = gorm.Expr("LEAST(next_sync_at, last_sync_at + make_interval(hours => ?))", *settings.SyncIntervalHours)
+	}
+	if settings.DefaultTheme != nil {
+		updates["default_theme"] = *settings.DefaultTheme
+	}
+	if settings.PublicHeatmap != nil {
+		updates["public_heatmap"] = *settings.PublicHeatmap
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	// Map updates so false values are written too
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(account).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update settings: %w", err)
+		}
+		// Pausing drops syncs queued before it, whatever queued them
+		if settings.IsActive != nil && !*settings.IsActive {
+			if err := tx.Where("docker_account_id = ? AND status = ?", account.ID, models.SyncJobStatusPending).Delete(&models.SyncJob{}).Error; err != nil {
+				return fmt.Errorf("failed to drop queued syncs: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// A sync already running is stopped too
+	if settings.IsActive != nil && !*settings.IsActive {
+		if err := s.CancelSync(account.ID); err != nil && !errors.Is(err, ErrNoSyncRunning) {
+			log.Printf("Failed to stop sync of paused account %d: %v", account.ID, err)
+		}
+	}
+	return database.DB.First(account, account.ID).Error
+}
+
+// getPublicAccount looks up an account by username for the public heatmap endpoints
+func (s *DockerHubService) getPublicAccount(dockerUsername string) (*models.DockerAccount, error) {
+	account, err := s.GetDockerAccountByUsername(dockerUsername)
+	if err != nil {
+		return nil, err
+	}
+	if !account.PublicHeatmap {
+		return nil, ErrHeatmapPrivate
+	}
+	return account, nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+	"time"
+
+	"docker-heatmap/internal/database"
+	"docker-heatmap/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// legacyEventKeyPrefix marks events stored before events carried an identity
+const legacyEventKeyPrefix = "legacy:"
+
+// activityEventKey hashes the parts that identify an event into a stable key
+func activityEventKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// pushEventKey identifies a single push of a tag (repo + tag + digest + pushed-at).
+// Timestamps are truncated to the second so webhook and API timestamps agree.
+func pushEventKey(repo, tag, digest string, pushedAt time.Time) string {
+	return activityEventKey(string(models.EventTypePush), repo, tag, digest, pushedAt.UTC().Truncate(time.Second).Format(time.RFC3339))
+}
+
+// newActivityEvent builds an event dated at midnight UTC of eventTime
+func newActivityEvent(accountID uint, namespace string, eventType models.EventType, eventTime time.Time, repo, tag, key string, count int) models.ActivityEvent {
+	return models.ActivityEvent{
+		DockerAccountID: accountID,
+		EventType:       eventType,
+		EventDate:       time.Date(eventTime.Year(), eventTime.Month(), eventTime.Day(), 0, 0, 0, 0, time.UTC),
+		Namespace:       namespace,
+		Repository:      repo,
+		Tag:             tag,
+		EventKey:        key,
+		Count:           count,
+	}
+}
+
+// recordActivity inserts the event unless one with the same key already exists
+// for the account, so re-ingesting the same data never changes counts (returns true if inserted)
+func recordActivity(tx *gorm.DB, event *models.ActivityEvent) (bool, error) {
+	// Adopt a row written before events had keys instead of counting the same day twice
+	adopted := tx.Model(&models.ActivityEvent{}).
+		Where("id = (?)", tx.Model(&models.ActivityEvent{}).Select("id").Where(
+			"docker_account_id = ? AND event_type = ? AND event_date = ? AND namespace = ? AND repository = ? AND tag = ? AND event_key LIKE ?",
+			event.DockerAccountID, event.EventType, event.EventDate, event.Namespace, event.Repository, event.Tag, legacyEventKeyPrefix+"%",
+		).Limit(1)).
+		Updates(map[string]interface{}{"event_key": event.EventKey, "count": event.Count})
+	if adopted.Error != nil {
+		return false, adopted.Error
+	}
+	if adopted.RowsAffected > 0 {
+		return false, nil
+	}
+
+	result := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "docker_account_id"}, {Name: "event_key"}},
+		DoNothing: true,
+	}).Create(event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// createActivity records an event for the account, logging failures (returns true if inserted)
+func (s *DockerHubService) createActivity(account *models.DockerAccount, event models.ActivityEvent) bool {
+	if event.Source == "" {
+		event.Source = account.Provider
+	}
+	created, err := recordActivity(database.DB, &event)
+	if err != nil {
+		log.Printf("Failed to create activity event for %s (%s %s:%s): %v", account.DockerUsername, event.EventType, event.Repository, event.Tag, err)
+		return false
+	}
+	return created
+}
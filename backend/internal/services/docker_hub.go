@@ -2,12 +2,11 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"docker-heatmap/internal/config"
@@ -22,82 +21,92 @@ var (
 	ErrDockerHubAuthFailed   = errors.New("docker hub authentication failed")
 	ErrDockerAccountNotFound = errors.New("docker account not found")
 	ErrDockerAccountExists   = errors.New("docker account already connected")
+	ErrDockerUsernameTaken   = errors.New("this Docker username is connected to another account")
 	ErrInvalidDockerToken    = errors.New("invalid docker hub access token")
 )
 
-// Use shared HTTP client from utils package
-var httpClient = utils.HTTPClient
+type DockerHubService struct {
+	hub *DockerHubProvider
+}
 
-// parseDockerTime parses Docker Hub's date format which includes microseconds
-func parseDockerTime(dateStr string) (time.Time, error) {
-	// Docker Hub uses ISO 8601 format with microseconds: 2026-01-17T08:19:30.340959Z
-	formats := []string{
-		"2006-01-02T15:04:05.999999Z",
-		"2006-01-02T15:04:05.999999-07:00",
-		time.RFC3339Nano,
-		time.RFC3339,
+func NewDockerHubService() *DockerHubService {
+	return &DockerHubService{
+		hub: NewDockerHubProvider(),
 	}
+}
 
-	for _, format := range formats {
-		if t, err := time.Parse(format, dateStr); err == nil {
-			return t, nil
-		}
+// ConnectAccount validates and connects a registry account.
+// It aggressively handles duplicates by cleaning up any previous records of the user, and lets an
+// owner the registry vouched for take over a username claimed without verification.
+func (s *DockerHubService) ConnectAccount(ctx context.Context, userID uint, providerName, registryURL, dockerUsername, accessToken string) (*models.DockerAccount, error) {
+	provider, err := GetProvider(providerName)
+	if err != nil {
+		return nil, err
 	}
 
-	return time.Time{}, fmt.Errorf("unable to parse date: %s", dateStr)
-}
+	// Only self-hosted providers take a registry URL
+	if selfHosted, ok := provider.(SelfHostedProvider); ok {
+		if registryURL, err = selfHosted.NormalizeRegistryURL(registryURL); err != nil {
+			return nil, err
+		}
+	} else {
+		registryURL = ""
+	}
 
-type DockerHubService struct {
-	apiURL string
-}
+	if err := provider.CheckCredentialFormat(dockerUsername, accessToken); err != nil {
+		return nil, err
+	}
 
-func NewDockerHubService() *DockerHubService {
-	return &DockerHubService{
-		apiURL: config.AppConfig.DockerHubAPIURL,
+	// 1. Validate the credentials with the registry before touching any existing claim
+	creds := RegistryCredentials{RegistryURL: registryURL, Username: dockerUsername, AccessToken: accessToken}
+	identity, err := provider.ValidateCredentials(ctx, creds)
+	if err != nil {
+		log.Printf("ConnectAccount failed: %v", err)
+		return nil, err
 	}
-}
 
-// DockerHubRepository represents a repository from Docker Hub API
-type DockerHubRepository struct {
-	Name        string `json:"name"`
-	Namespace   string `json:"namespace"`
-	Description string `json:"description"`
-	LastUpdated string `json:"last_updated"` // ISO string
-	PullCount   int64  `json:"pull_count"`
-	StarCount   int    `json:"star_count"`
-	IsPrivate   bool   `json:"is_private"`
-}
+	// A valid token proves nothing unless it was issued to this username
+	verified, err := tokenOwnership(provider, identity, dockerUsername)
+	if err != nil {
+		log.Printf("ConnectAccount: token for %s authenticates as %q", dockerUsername, identity.Username)
+		return nil, err
+	}
 
-// DockerHubTag represents a tag from Docker Hub API
-type DockerHubTag struct {
-	Name          string `json:"name"`
-	LastUpdated   string `json:"last_updated"`
-	TagLastPushed string `json:"tag_last_pushed"`
-	Digest        string `json:"digest"`
-}
+	// 2. Encrypt the access token
+	encryptedToken, iv, err := utils.Encrypt(accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("encryption failed: %w", err)
+	}
 
-// ConnectAccount validates and connects a Docker Hub account.
-// It aggressively handles duplicates by cleaning up any previous records for the user or username.
-func (s *DockerHubService) ConnectAccount(ctx context.Context, userID uint, dockerUsername, accessToken string) (*models.DockerAccount, error) {
 	var account models.DockerAccount
 
 	// Use a transaction to ensure deletion and creation are atomic
-	err := database.DB.Transaction(func(tx *gorm.DB) error {
-		// 1. Check for username conflict with OTHER users (including soft-deleted)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		accountIDs := []uint{}
+		tx.Unscoped().Model(&models.DockerAccount{}).Where("user_id = ?", userID).Pluck("id", &accountIDs)
+
+		// 3. The same registry username held by another user (including soft-deleted) blocks the
+		// connection, unless this token proves ownership and the existing claim never did
 		var conflict models.DockerAccount
-		if err := tx.Unscoped().Where("docker_username = ? AND user_id != ?", dockerUsername, userID).First(&conflict).Error; err == nil {
-			return errors.New("this Docker username is connected to another account")
+		err := tx.Unscoped().
+			Where("provider = ? AND registry_url = ? AND docker_username = ? AND user_id != ?", provider.Name(), registryURL, dockerUsername, userID).
+			First(&conflict).Error
+		if err == nil {
+			if conflict.OwnerVerified || !verified {
+				return ErrDockerUsernameTaken
+			}
+			log.Printf("ConnectAccount: verified owner of %s replaces unverified account ID=%d of User=%d", dockerUsername, conflict.ID, conflict.UserID)
+			accountIDs = append(accountIDs, conflict.ID)
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to check username: %w", err)
 		}
 
-		// 2. Clear any existing/orphaned records and their events
-		var accountIDs []uint
-		tx.Unscoped().Model(&models.DockerAccount{}).Where("user_id = ? OR docker_username = ?", userID, dockerUsername).Pluck("id", &accountIDs)
-
+		// 4. Clear any existing/orphaned records and their events
 		if len(accountIDs) > 0 {
 			log.Printf("ConnectAccount: Found %d existing records to delete (IDs: %v)", len(accountIDs), accountIDs)
-			// Delete events first to satisfy foreign key constraints
-			if err := tx.Unscoped().Where("docker_account_id IN ?", accountIDs).Delete(&models.ActivityEvent{}).Error; err != nil {
-				return fmt.Errorf("failed to clear old events: %w", err)
+			// Delete dependent rows first to satisfy foreign key constraints
+			if err := deleteAccountData(tx, accountIDs); err != nil {
+				return err
 			}
 			// Delete the accounts
 			if err := tx.Unscoped().Where("id IN ?", accountIDs).Delete(&models.DockerAccount{}).Error; err != nil {
@@ -106,21 +115,14 @@ func (s *DockerHubService) ConnectAccount(ctx context.Context, userID uint, dock
 			log.Printf("ConnectAccount: Successfully cleared records for UserID=%d and DockerUser=%s", userID, dockerUsername)
 		}
 
-		// 3. Validate the Docker Hub username exists
-		if err := s.validateUsername(ctx, dockerUsername); err != nil {
-			return err
-		}
-
-		// 4. Encrypt the access token
-		encryptedToken, iv, err := utils.Encrypt(accessToken)
-		if err != nil {
-			return fmt.Errorf("encryption failed: %w", err)
-		}
-
 		// 5. Create a fresh account record
 		account = models.DockerAccount{
 			UserID:         userID,
+			Provider:       provider.Name(),
+			RegistryURL:    registryURL,
+			TokenScopes:    strings.Join(identity.Scopes, " "),
 			DockerUsername: dockerUsername,
+			OwnerVerified:  verified,
 			EncryptedToken: encryptedToken,
 			TokenIV:        iv,
 			IsActive:       true,
@@ -139,48 +141,14 @@ func (s *DockerHubService) ConnectAccount(ctx context.Context, userID uint, dock
 		return nil, err
 	}
 
-	log.Printf("Docker account connected: ID=%d, Username=%s for User=%d", account.ID, dockerUsername, userID)
+	log.Printf("Docker account connected: ID=%d, Provider=%s, Username=%s for User=%d", account.ID, account.Provider, dockerUsername, userID)
 
-	// 6. Trigger initial sync
-	go func() {
-		syncCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-		defer cancel()
-		if err := s.SyncActivity(syncCtx, account.ID); err != nil {
-			log.Printf("Initial sync failed for account %d: %v", account.ID, err)
-		}
-	}()
-
-	return &account, nil
-}
-
-// validateUsername checks if a Docker Hub username exists
-func (s *DockerHubService) validateUsername(ctx context.Context, username string) error {
-	url := fmt.Sprintf("%s/users/%s", s.apiURL, username)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		log.Printf("Docker Hub API request failed: %v", err)
-		return ErrDockerHubAuthFailed
+	// 6. Queue the initial sync
+	if _, err := s.EnqueueSync(account.ID, models.SyncTriggerConnect); err != nil {
+		log.Printf("Failed to queue initial sync for account %d: %v", account.ID, err)
 	}
-	defer resp.Body.Close()
 
-	log.Printf("Docker Hub user lookup: status=%d for user=%s", resp.StatusCode, username)
-
-	if resp.StatusCode == http.StatusNotFound {
-		return errors.New("Docker Hub user not found")
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return ErrDockerHubAuthFailed
-	}
-
-	return nil
+	return &account, nil
 }
 
 // GetDockerAccount retrieves a user's Docker account
@@ -192,231 +160,430 @@ func (s *DockerHubService) GetDockerAccount(userID uint) (*models.DockerAccount,
 	return &account, nil
 }
 
-// GetDockerAccountByUsername retrieves a Docker account by Docker username
+// GetDockerAccountByUsername retrieves a Docker Hub account by username. Public URLs name
+// Docker Hub users; accounts of other registries are not addressable by their username.
 func (s *DockerHubService) GetDockerAccountByUsername(dockerUsername string) (*models.DockerAccount, error) {
 	var account models.DockerAccount
-	if err := database.DB.Where("docker_username = ?", dockerUsername).First(&account).Error; err != nil {
+	err := database.DB.Where("provider = ? AND registry_url = '' AND docker_username = ?", ProviderDockerHub, dockerUsername).First(&account).Error
+	if err != nil {
 		return nil, ErrDockerAccountNotFound
 	}
 	return &account, nil
 }
 
-// FetchRepositories fetches repositories for a Docker Hub user
-func (s *DockerHubService) FetchRepositories(ctx context.Context, username, token string) ([]DockerHubRepository, error) {
-	url := fmt.Sprintf("%s/repositories/%s?page_size=100", s.apiURL, username)
+// syncState accumulates the results of one sync across namespaces.
+// Repositories are synced concurrently, so counters are only touched through its methods.
+type syncState struct {
+	account  *models.DockerAccount
+	provider RegistryProvider
+	creds    RegistryCredentials
+	syncTime time.Time
+
+	mu            sync.Mutex
+	truncated     bool
+	reposScanned  int
+	tagsScanned   int
+	eventsCreated int
+	runErrors     []models.SyncRunError
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
+// markTruncated records that a page ceiling cut a listing short
+func (st *syncState) markTruncated() {
+	st.mu.Lock()
+	st.truncated = true
+	st.mu.Unlock()
+}
+
+// eventCreated counts one newly written activity event
+func (st *syncState) eventCreated() {
+	st.mu.Lock()
+	st.eventsCreated++
+	st.mu.Unlock()
+}
+
+// scanned counts listed repositories and tags
+func (st *syncState) scanned(repos, tags int) {
+	st.mu.Lock()
+	st.reposScanned += repos
+	st.tagsScanned += tags
+	st.mu.Unlock()
+}
+
+// repoError records a failure of one namespace (repo empty) or repository
+func (st *syncState) repoError(namespace, repo string, err error) {
+	st.mu.Lock()
+	st.runErrors = append(st.runErrors, models.SyncRunError{Namespace: namespace, Repository: repo, Message: err.Error()})
+	st.mu.Unlock()
+}
+
+// SyncActivity syncs registry activity for an account through its provider and records the run
+func (s *DockerHubService) SyncActivity(ctx context.Context, accountID uint, trigger models.SyncTrigger) (err error) {
+	var account models.DockerAccount
+	if err := database.DB.First(&account, accountID).Error; err != nil {
+		return fmt.Errorf("account not found: %w", err)
 	}
-	req.Header.Set("Accept", "application/json")
-	if token != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	// Jobs queued before the account was paused must not sync it
+	if !account.IsActive {
+		return ErrAccountPaused
 	}
 
-	client := httpClient
-	resp, err := client.Do(req)
+	ctx, release, err := registerSync(ctx, account.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch repositories: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
+	defer release()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Docker Hub API error: status=%d body=%s", resp.StatusCode, string(body))
-		return nil, fmt.Errorf("docker hub api returned status %d", resp.StatusCode)
+	// The lease keeps other replicas (and the worker) from syncing the account at the same time
+	lease, err := acquireSyncLease(account.ID)
+	if err != nil {
+		return err
 	}
+	ctx, abort := context.WithCancelCause(ctx)
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+	go lease.keepAlive(heartbeatCtx, abort)
+	defer func() {
+		stopHeartbeat()
+		lease.release()
+		abort(nil)
+	}()
 
-	var result struct {
-		Count   int                   `json:"count"`
-		Results []DockerHubRepository `json:"results"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	log.Printf("Starting sync for account ID=%d provider=%s username=%s trigger=%s", account.ID, account.Provider, account.DockerUsername, trigger)
+
+	state := &syncState{
+		account:  &account,
+		syncTime: time.Now().UTC(),
 	}
+	interruptPreviousSyncRuns(account.ID)
+	run := startSyncRun(account.ID, trigger)
+	aborted := false
 
-	log.Printf("Fetched %d repositories for %s", len(result.Results), username)
-	return result.Results, nil
-}
+	// Status columns are written explicitly so a successful run always clears the previous error
+	defer func() {
+		updates := map[string]interface{}{}
+
+		if err != nil && errors.Is(context.Cause(ctx), ErrSyncLeaseLost) {
+			// Another worker owns the account now and will record its own outcome
+			err = ErrSyncLeaseLost
+			finishSyncRun(run, state, err, true)
+			return
+		} else if err != nil && syncInterrupted(ctx) {
+			// Like a cancelled sync, but the queue runs it again after the restart
+			err = ErrSyncInterrupted
+			updates["last_sync_error"] = err.Error()
+		} else if err != nil && syncCancelled(ctx) {
+			// A cancelled sync is incomplete: keep the previous sync time, but wait for the next
+			// scheduled slot instead of being queued again right away
+			err = ErrSyncCancelled
+			updates["last_sync_error"] = err.Error()
+			updates["next_sync_at"] = nextSyncAt(&account, time.Now())
+		} else {
+			lastSyncError := ""
+			if err != nil {
+				lastSyncError = err.Error()
+				if aborted {
+					lastSyncError = syncErrorMessage(err)
+				}
+			}
+			now := time.Now()
+			updates["sync_truncated"] = state.truncated
+			updates["last_sync_at"] = now
+			updates["last_sync_error"] = lastSyncError
+			updates["next_sync_at"] = nextSyncAt(&account, now)
+		}
 
-// FetchTags fetches tags for a specific repository
-func (s *DockerHubService) FetchTags(ctx context.Context, username, repoName, token string) ([]DockerHubTag, error) {
-	url := fmt.Sprintf("%s/repositories/%s/%s/tags?page_size=100", s.apiURL, username, repoName)
+		database.DB.Model(&account).Updates(updates)
+		finishSyncRun(run, state, err, aborted)
+		log.Printf("Sync completed for account ID=%d", account.ID)
+	}()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	state.provider, err = GetProvider(account.Provider)
 	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Accept", "application/json")
-	if token != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		aborted = true
+		return err
 	}
 
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	// Decrypt token
+	token, decryptErr := utils.Decrypt(account.EncryptedToken, account.TokenIV)
+	if decryptErr != nil {
+		log.Printf("Failed to decrypt token for account %d: %v", account.ID, decryptErr)
+		// Continue anyway, maybe public access works
+		token = ""
 	}
-	defer resp.Body.Close()
+	state.creds = accountCredentials(&account, token)
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("docker hub api returned status %d", resp.StatusCode)
-	}
+	var syncErrors []error
+	for _, namespace := range s.accountNamespaces(&account) {
+		nsErr := s.syncNamespace(ctx, state, namespace)
+		if nsErr == nil {
+			continue
+		}
 
-	var result struct {
-		Results []DockerHubTag `json:"results"`
+		log.Printf("Failed to sync namespace %s for account %d: %v", namespace, account.ID, nsErr)
+		// Credential and cancellation failures affect every namespace
+		if isAuthError(nsErr) || ctx.Err() != nil {
+			aborted = true
+			return nsErr
+		}
+		state.repoError(namespace, "", nsErr)
+		syncErrors = append(syncErrors, fmt.Errorf("%s: %w", namespace, nsErr))
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+
+	// GitHub Container Registry packages ride along with the account's registry
+	if ghcrErr := s.syncGHCR(ctx, state); ghcrErr != nil {
+		log.Printf("Failed to sync GitHub packages for account %d: %v", account.ID, ghcrErr)
+		if ctx.Err() != nil {
+			aborted = true
+			return ghcrErr
+		}
+		state.repoError(ProviderGHCR, "", ghcrErr)
+		syncErrors = append(syncErrors, fmt.Errorf("ghcr: %w", ghcrErr))
 	}
 
-	return result.Results, nil
+	log.Printf("Created %d new activity events for %s", state.eventsCreated, account.DockerUsername)
+
+	// The failed namespaces are recorded on the run; everything else was synced
+	if len(syncErrors) > 0 {
+		return fmt.Errorf("%w: %w", ErrSyncPartial, errors.Join(syncErrors...))
+	}
+	return nil
 }
 
-// SyncActivity syncs Docker Hub activity for an account
-func (s *DockerHubService) SyncActivity(ctx context.Context, accountID uint) error {
-	var account models.DockerAccount
-	if err := database.DB.First(&account, accountID).Error; err != nil {
-		return fmt.Errorf("account not found: %w", err)
+// syncNamespace records activity for every repository in one namespace,
+// fetching tags for up to SyncConcurrency repositories at a time
+func (s *DockerHubService) syncNamespace(ctx context.Context, state *syncState, namespace string) error {
+	account := state.account
+
+	// Fetch repositories
+	repos, reposTruncated, err := state.provider.ListRepositories(ctx, state.creds, namespace)
+	if reposTruncated {
+		log.Printf("Repository listing for %s hit the page limit", namespace)
+		state.markTruncated()
+	}
+	if err != nil {
+		return err
 	}
 
-	log.Printf("Starting sync for account ID=%d username=%s", account.ID, account.DockerUsername)
+	log.Printf("Processing %d repositories for %s", len(repos), namespace)
+	state.scanned(len(repos), 0)
 
-	// Mark sync in progress
-	account.SyncInProgress = true
-	database.DB.Save(&account)
+	cursors := loadRepositoryCursors(account.ID, namespace)
 
-	defer func() {
-		account.SyncInProgress = false
-		now := time.Now()
-		account.LastSyncAt = &now
-		database.DB.Save(&account)
-		log.Printf("Sync completed for account ID=%d", account.ID)
-	}()
+	// An auth failure in one repository stops the others early
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	// Decrypt token
-	token, err := utils.Decrypt(account.EncryptedToken, account.TokenIV)
-	if err != nil {
-		log.Printf("Failed to decrypt token for account %d: %v", account.ID, err)
-		// Continue anyway, maybe public access works
-		token = ""
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	jobs := make(chan RegistryRepository)
+	for i := 0; i < config.AppConfig.SyncConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for repo := range jobs {
+				if err := s.syncRepository(workCtx, state, namespace, repo, cursors[repo.Name]); err != nil {
+					errOnce.Do(func() { firstErr = err })
+					cancel()
+				}
+			}
+		}()
 	}
 
-	// Fetch repositories
-	repos, err := s.FetchRepositories(ctx, account.DockerUsername, token)
-	if err != nil {
-		account.LastSyncError = err.Error()
-		log.Printf("Failed to fetch repositories for %s: %v", account.DockerUsername, err)
-		return err
+feed:
+	for _, repo := range repos {
+		select {
+		case jobs <- repo:
+		case <-workCtx.Done():
+			break feed
+		}
 	}
+	close(jobs)
+	wg.Wait()
 
-	if len(repos) == 0 {
-		log.Printf("No repositories found for %s", account.DockerUsername)
-		account.LastSyncError = ""
-		return nil
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// syncRepository records the activity of one repository. Only errors that should abort
+// the whole sync (credentials, cancellation) are returned; others are logged.
+func (s *DockerHubService) syncRepository(ctx context.Context, state *syncState, namespace string, repo RegistryRepository, cursor *models.RepositoryCursor) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	log.Printf("Processing %d repositories for %s", len(repos), account.DockerUsername)
+	account := state.account
 
-	eventsCreated := 0
+	log.Printf("Processing repo: %s/%s, last_updated: %s", namespace, repo.Name, repo.LastUpdated)
+	repoKey := qualifiedRepo(account, namespace, repo.Name)
 
-	// Process each repository to extract activity
-	for _, repo := range repos {
-		log.Printf("Processing repo: %s, last_updated: %s", repo.Name, repo.LastUpdated)
+	// Diff pull_count against the previous sync to produce pull activity
+	if repo.PullCount >= 0 && s.recordPullCount(account, namespace, repo.Name, repo.PullCount, state.syncTime) {
+		state.eventCreated()
+	}
 
-		// Parse and create push event based on last_updated
-		if repo.LastUpdated != "" {
-			parsedTime, err := parseDockerTime(repo.LastUpdated)
-			if err != nil {
-				log.Printf("Failed to parse date %s: %v", repo.LastUpdated, err)
-			} else if s.createActivity(&account, models.EventTypePush, parsedTime, repo.Name, "") {
-				eventsCreated++
-			}
+	// Nothing was pushed since the last scan
+	now := time.Now()
+	marker := repositoryMarker(ctx, state, namespace, repo)
+	if repositoryUnchanged(cursor, marker, now) {
+		return nil
+	}
+
+	// Fetch tags and create one push event per tag push
+	since := tagCutoff(cursor, now)
+	tags, tagsTruncated, err := state.provider.ListTags(ctx, state.creds, namespace, repo.Name, since)
+	if tagsTruncated {
+		log.Printf("Tag listing for %s/%s hit the page limit", namespace, repo.Name)
+		state.markTruncated()
+	}
+	if err != nil {
+		log.Printf("Failed to fetch tags for %s/%s: %v", namespace, repo.Name, err)
+		if isAuthError(err) || ctx.Err() != nil {
+			return err
 		}
+		state.repoError(namespace, repo.Name, err)
+		return nil
+	}
+	state.scanned(0, len(tags))
 
-		// Fetch tags and create push events for each
-		tags, err := s.FetchTags(ctx, account.DockerUsername, repo.Name, token)
+	// Untagged repositories only have last_updated to go on; otherwise it
+	// just mirrors the newest tag push and would count it twice
+	if len(tags) == 0 && repo.LastUpdated != "" {
+		parsedTime, err := state.provider.ParseTimestamp(repo.LastUpdated)
 		if err != nil {
-			log.Printf("Failed to fetch tags for %s/%s: %v", account.DockerUsername, repo.Name, err)
-			continue
+			log.Printf("Failed to parse date %s: %v", repo.LastUpdated, err)
+		} else if s.createActivity(account, newActivityEvent(account.ID, namespace, models.EventTypePush, parsedTime, repo.Name, "", pushEventKey(repoKey, "", "", parsedTime), 1)) {
+			state.eventCreated()
 		}
+	}
+
+	next := models.RepositoryCursor{
+		DockerAccountID: account.ID,
+		Namespace:       namespace,
+		Repository:      repo.Name,
+		LastUpdated:     marker,
+	}
+	if cursor != nil {
+		next.LastTagPushAt = cursor.LastTagPushAt
+		next.FullScanAt = cursor.FullScanAt
+	}
+	if since.IsZero() {
+		next.FullScanAt = now
+	}
 
-		for _, tag := range tags {
-			// Use tag_last_pushed
-			if tag.TagLastPushed != "" {
-				parsedTime, err := parseDockerTime(tag.TagLastPushed)
-				if err != nil {
-					log.Printf("Failed to parse tag date %s: %v", tag.TagLastPushed, err)
-				} else if s.createActivity(&account, models.EventTypePush, parsedTime, repo.Name, tag.Name) {
-					eventsCreated++
+	for _, tag := range tags {
+		// Use the tag's last push
+		if tag.PushedAt != "" {
+			parsedTime, err := state.provider.ParseTimestamp(tag.PushedAt)
+			if err != nil {
+				log.Printf("Failed to parse tag date %s: %v", tag.PushedAt, err)
+			} else {
+				if next.LastTagPushAt == nil || parsedTime.After(*next.LastTagPushAt) {
+					pushedAt := parsedTime
+					next.LastTagPushAt = &pushedAt
 				}
+				s.recordTagRevision(account, namespace, repo.Name, tag.Name, tag.Digest, parsedTime)
+				if s.createActivity(account, newActivityEvent(account.ID, namespace, models.EventTypePush, parsedTime, repo.Name, tag.Name, pushEventKey(repoKey, tag.Name, tag.Digest, parsedTime), 1)) {
+					state.eventCreated()
+				}
+			}
+		}
+
+		// The last pull marks the tag as in use that day
+		if tag.LastPulled != "" {
+			parsedTime, err := state.provider.ParseTimestamp(tag.LastPulled)
+			if err != nil {
+				log.Printf("Failed to parse tag pull date %s: %v", tag.LastPulled, err)
+			} else if s.recordPullDay(account, namespace, repo.Name, tag.Name, parsedTime) {
+				state.eventCreated()
 			}
 		}
 	}
 
-	log.Printf("Created/updated %d activity events for %s", eventsCreated, account.DockerUsername)
-	account.LastSyncError = ""
+	// A listing cut short by the page ceiling may hide older tags; rescan it next time
+	if !tagsTruncated {
+		saveRepositoryCursor(&next)
+	}
 	return nil
 }
 
-// createActivity creates an activity event (returns true if created)
-func (s *DockerHubService) createActivity(account *models.DockerAccount, eventType models.EventType, eventDate time.Time, repo, tag string) bool {
-	// Normalize to date only (midnight UTC)
-	normalizedDate := time.Date(
-		eventDate.Year(),
-		eventDate.Month(),
-		eventDate.Day(),
-		0, 0, 0, 0,
-		time.UTC,
-	)
-
-	// Check if event already exists
-	var existing models.ActivityEvent
-	err := database.DB.Where(
-		"docker_account_id = ? AND event_date = ? AND repository = ? AND tag = ?",
-		account.ID, normalizedDate, repo, tag,
-	).First(&existing).Error
+// ActivityFilter selects which namespaces contribute to activity data
+type ActivityFilter struct {
+	IncludeOrgs bool   // Include organization namespaces alongside the account's own
+	Namespace   string // Only this namespace (takes precedence over IncludeOrgs)
+}
 
-	if err == nil {
-		// Event already exists, update count
-		existing.Count++
-		database.DB.Save(&existing)
-		return false
+// scope restricts an activity_events query to the filter's namespaces
+func (f ActivityFilter) scope(query *gorm.DB, account *models.DockerAccount) *gorm.DB {
+	switch {
+	case f.Namespace != "":
+		return query.Where("namespace = ?", f.Namespace)
+	case f.IncludeOrgs:
+		return query
+	default:
+		// GitHub packages belong to the user rather than a registry namespace, so they always count
+		return query.Where("(namespace IN ? OR source = ?)", []string{"", account.DockerUsername}, ProviderGHCR)
 	}
+}
 
-	// Create new event
-	event := models.ActivityEvent{
-		DockerAccountID: account.ID,
-		EventType:       eventType,
-		EventDate:       normalizedDate,
-		Repository:      repo,
-		Tag:             tag,
-		Count:           1,
+// activityRange returns the [start, end] window covered by the last `days` days
+func activityRange(days int) (time.Time, time.Time) {
+	startDate := time.Now().UTC().AddDate(0, 0, -days)
+	startDate = time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+	return startDate, time.Now().UTC()
+}
+
+// GetNamespaceTotals returns total activity per namespace over the last `days` days
+func (s *DockerHubService) GetNamespaceTotals(dockerUsername string, days int) (map[string]int, error) {
+	account, err := s.getPublicAccount(dockerUsername)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := database.DB.Create(&event).Error; err != nil {
-		log.Printf("Failed to create activity event: %v", err)
-		return false
+	startDate, endDate := activityRange(days)
+
+	var rows []struct {
+		Namespace string
+		Total     int
+	}
+	err = database.DB.Model(&models.ActivityEvent{}).
+		Select("namespace, SUM(count) AS total").
+		Where("docker_account_id = ? AND event_date >= ? AND event_date <= ? AND event_type <> ?", account.ID, startDate, endDate, models.EventTypePullDay).
+		Group("namespace").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
 	}
 
-	return true
+	totals := make(map[string]int, len(rows))
+	for _, row := range rows {
+		namespace := row.Namespace
+		if namespace == "" {
+			namespace = account.DockerUsername
+		}
+		totals[namespace] += row.Total
+	}
+	return totals, nil
 }
 
 // GetActivitySummary returns aggregated activity data for heatmap
-func (s *DockerHubService) GetActivitySummary(dockerUsername string, days int) ([]models.ActivitySummary, error) {
-	account, err := s.GetDockerAccountByUsername(dockerUsername)
+func (s *DockerHubService) GetActivitySummary(dockerUsername string, days int, filter ActivityFilter) ([]models.ActivitySummary, error) {
+	account, err := s.getPublicAccount(dockerUsername)
 	if err != nil {
 		return nil, err
 	}
 
-	startDate := time.Now().UTC().AddDate(0, 0, -days)
-	startDate = time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
-	endDate := time.Now().UTC()
+	startDate, endDate := activityRange(days)
 
 	// Query activity events
 	var events []models.ActivityEvent
-	err = database.DB.Where(
-		"docker_account_id = ? AND event_date >= ? AND event_date <= ?",
-		account.ID, startDate, endDate,
+	err = filter.scope(database.DB, account).Where(
+		"docker_account_id = ? AND event_date >= ? AND event_date <= ? AND event_type <> ?",
+		account.ID, startDate, endDate, models.EventTypePullDay,
 	).Find(&events).Error
 
 	if err != nil {
@@ -497,8 +664,10 @@ func calculateLevel(count, maxCount int) int {
 
 // DisconnectAccount removes a Docker Hub account permanently
 func (s *DockerHubService) DisconnectAccount(userID, accountID uint) error {
-	// Permanently delete all activity events (use Unscoped to bypass soft delete)
-	database.DB.Unscoped().Where("docker_account_id = ?", accountID).Delete(&models.ActivityEvent{})
+	// Permanently delete all activity data (use Unscoped to bypass soft delete)
+	if err := deleteAccountData(database.DB, []uint{accountID}); err != nil {
+		log.Printf("Failed to delete data for docker account ID=%d: %v", accountID, err)
+	}
 
 	// Permanently delete the docker account (use Unscoped to bypass soft delete)
 	result := database.DB.Unscoped().Where("id = ? AND user_id = ?", accountID, userID).Delete(&models.DockerAccount{})
@@ -509,3 +678,32 @@ func (s *DockerHubService) DisconnectAccount(userID, accountID uint) error {
 	log.Printf("Permanently deleted docker account ID=%d for user ID=%d", accountID, userID)
 	return nil
 }
+
+// deleteAccountData permanently removes every row that references the given accounts
+func deleteAccountData(tx *gorm.DB, accountIDs []uint) error {
+	if err := tx.Unscoped().Where("docker_account_id IN ?", accountIDs).Delete(&models.ActivityEvent{}).Error; err != nil {
+		return fmt.Errorf("failed to clear old events: %w", err)
+	}
+	if err := tx.Where("docker_account_id IN ?", accountIDs).Delete(&models.RepositoryPullSnapshot{}).Error; err != nil {
+		return fmt.Errorf("failed to clear pull snapshots: %w", err)
+	}
+	if err := tx.Where("docker_account_id IN ?", accountIDs).Delete(&models.TagRevision{}).Error; err != nil {
+		return fmt.Errorf("failed to clear tag revisions: %w", err)
+	}
+	if err := tx.Where("docker_account_id IN ?", accountIDs).Delete(&models.DockerNamespace{}).Error; err != nil {
+		return fmt.Errorf("failed to clear namespaces: %w", err)
+	}
+	if err := tx.Where("docker_account_id IN ?", accountIDs).Delete(&models.RepositoryCursor{}).Error; err != nil {
+		return fmt.Errorf("failed to clear repository cursors: %w", err)
+	}
+	if err := tx.Where("sync_run_id IN (?)", tx.Model(&models.SyncRun{}).Select("id").Where("docker_account_id IN ?", accountIDs)).Delete(&models.SyncRunError{}).Error; err != nil {
+		return fmt.Errorf("failed to clear sync run errors: %w", err)
+	}
+	if err := tx.Where("docker_account_id IN ?", accountIDs).Delete(&models.SyncJob{}).Error; err != nil {
+		return fmt.Errorf("failed to clear sync jobs: %w", err)
+	}
+	if err := tx.Where("docker_account_id IN ?", accountIDs).Delete(&models.SyncRun{}).Error; err != nil {
+		return fmt.Errorf("failed to clear sync runs: %w", err)
+	}
+	return nil
+}
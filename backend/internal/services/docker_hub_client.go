@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"docker-heatmap/internal/config"
+	"docker-heatmap/internal/utils"
+)
+
+const (
+	hubBackoffBase = time.Second
+	hubBackoffMax  = time.Minute
+	// hubMaxPause caps how long a single Retry-After or reset header may stall requests
+	hubMaxPause = 15 * time.Minute
+)
+
+// RateLimitStatus is a snapshot of the Docker Hub rate budget as last reported by the API
+type RateLimitStatus struct {
+	Limit       int        `json:"limit"`     // -1 when Docker Hub has not reported one yet
+	Remaining   int        `json:"remaining"` // -1 when Docker Hub has not reported one yet
+	ResetAt     *time.Time `json:"reset_at,omitempty"`
+	PausedUntil *time.Time `json:"paused_until,omitempty"`
+}
+
+// DockerHubClient wraps an HTTP client with Docker Hub rate-limit handling.
+// It reads the X-RateLimit-* and Retry-After headers, retries 429/5xx responses
+// with exponential backoff and jitter, and holds requests back while the budget is spent.
+type DockerHubClient struct {
+	http       *http.Client
+	maxRetries int
+
+	mu          sync.Mutex
+	limit       int
+	remaining   int
+	resetAt     time.Time
+	pausedUntil time.Time
+}
+
+// One client per process so that every concurrent sync draws from the same budget
+var (
+	hubClient     *DockerHubClient
+	hubClientOnce sync.Once
+)
+
+func NewDockerHubClient(httpClient *http.Client, maxRetries int) *DockerHubClient {
+	return &DockerHubClient{
+		http:       httpClient,
+		maxRetries: maxRetries,
+		limit:      -1,
+		remaining:  -1,
+	}
+}
+
+// SharedDockerHubClient returns the process-wide Docker Hub client
+func SharedDockerHubClient() *DockerHubClient {
+	hubClientOnce.Do(func() {
+		hubClient = NewDockerHubClient(utils.HTTPClient, config.AppConfig.DockerHubMaxRetries)
+	})
+	return hubClient
+}
+
+// DockerHubQuota reports the remaining Docker Hub rate budget for observability
+func DockerHubQuota() RateLimitStatus {
+	return SharedDockerHubClient().Quota()
+}
+
+// Quota returns the current rate budget snapshot
+func (c *DockerHubClient) Quota() RateLimitStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := RateLimitStatus{Limit: c.limit, Remaining: c.remaining}
+	if !c.resetAt.IsZero() {
+		resetAt := c.resetAt
+		status.ResetAt = &resetAt
+	}
+	if time.Now().Before(c.pausedUntil) {
+		pausedUntil := c.pausedUntil
+		status.PausedUntil = &pausedUntil
+	}
+	return status
+}
+
+// Do sends the request, waiting for budget first and retrying throttled or failed attempts.
+// A 429 or 5xx that survives every retry is returned to the caller as a normal response.
+func (c *DockerHubClient) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if err := c.waitForBudget(ctx); err != nil {
+			return nil, err
+		}
+
+		attemptReq := req.Clone(ctx)
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq.Body = body
+		}
+
+		resp, err := c.http.Do(attemptReq)
+		if err != nil {
+			if ctx.Err() != nil || attempt >= c.maxRetries {
+				return nil, err
+			}
+			wait := backoff(attempt)
+			log.Printf("Docker Hub request failed (attempt %d): %v - retrying in %s", attempt+1, err, wait)
+			if err := sleepContext(ctx, wait); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		c.observe(resp)
+
+		if !retryableStatus(resp.StatusCode) || attempt >= c.maxRetries {
+			return resp, nil
+		}
+
+		wait, ok := retryAfter(resp)
+		if !ok {
+			wait = backoff(attempt)
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			c.pause(wait)
+		}
+
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+
+		log.Printf("Docker Hub returned %d for %s (attempt %d) - retrying in %s", resp.StatusCode, req.URL.Path, attempt+1, wait)
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// waitForBudget blocks while the client is paused or the reported budget is exhausted,
+// then reserves one request from the remaining budget
+func (c *DockerHubClient) waitForBudget(ctx context.Context) error {
+	for {
+		c.mu.Lock()
+		now := time.Now()
+		var wait time.Duration
+		switch {
+		case now.Before(c.pausedUntil):
+			wait = c.pausedUntil.Sub(now)
+		case c.remaining == 0 && now.Before(c.resetAt):
+			wait = c.resetAt.Sub(now)
+		default:
+			if c.remaining > 0 {
+				c.remaining--
+			}
+			c.mu.Unlock()
+			return nil
+		}
+		c.mu.Unlock()
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// observe records the rate-limit headers of a response
+func (c *DockerHubClient) observe(resp *http.Response) {
+	limit, hasLimit := headerInt(resp, "X-RateLimit-Limit")
+	remaining, hasRemaining := headerInt(resp, "X-RateLimit-Remaining")
+	reset, hasReset := headerInt(resp, "X-RateLimit-Reset")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if hasLimit {
+		c.limit = limit
+	}
+	if hasRemaining {
+		c.remaining = remaining
+	}
+	if hasReset {
+		resetAt := time.Unix(int64(reset), 0)
+		if maxReset := time.Now().Add(hubMaxPause); resetAt.After(maxReset) {
+			resetAt = maxReset
+		}
+		c.resetAt = resetAt
+	} else if c.remaining == 0 && c.resetAt.Before(time.Now()) {
+		// Budget exhausted with no reset hint: back off rather than spin
+		c.resetAt = time.Now().Add(hubBackoffMax)
+	}
+}
+
+// pause holds back every request on this client for the given duration
+func (c *DockerHubClient) pause(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(c.pausedUntil) {
+		c.pausedUntil = until
+	}
+}
+
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// retryAfter parses the Retry-After header, which may be seconds or an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		wait = time.Until(t)
+	} else {
+		return 0, false
+	}
+
+	if wait < 0 {
+		wait = 0
+	}
+	if wait > hubMaxPause {
+		wait = hubMaxPause
+	}
+	return wait, true
+}
+
+// backoff returns an exponential delay for the given attempt with jitter in [d/2, d)
+func backoff(attempt int) time.Duration {
+	d := hubBackoffBase << attempt
+	if d <= 0 || d > hubBackoffMax {
+		d = hubBackoffMax
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
+
+func headerInt(resp *http.Response, key string) (int, bool) {
+	value := resp.Header.Get(key)
+	if value == "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// sleepContext waits for d or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
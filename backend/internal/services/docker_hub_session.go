@@ -0,0 +1,193 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// hubSessionFallbackTTL is used when the session JWT carries no readable expiry
+	hubSessionFallbackTTL = 10 * time.Minute
+	// hubSessionRefreshMargin renews sessions slightly before Docker Hub expires them
+	hubSessionRefreshMargin = time.Minute
+	// authSyncErrorPrefix marks LastSyncError values caused by credential problems
+	authSyncErrorPrefix = "auth: "
+)
+
+// hubSession is a cached Docker Hub session JWT obtained by exchanging a PAT
+type hubSession struct {
+	token       string
+	fingerprint string // Hash of the PAT the session was issued for
+	expiresAt   time.Time
+}
+
+// Session cache keyed by Docker username, shared by every DockerHubService instance
+var (
+	hubSessions   = make(map[string]hubSession)
+	hubSessionsMu sync.Mutex
+)
+
+// hubAuth carries the credentials used to authorize Docker Hub API calls for one account
+type hubAuth struct {
+	provider    *DockerHubProvider
+	username    string
+	accessToken string
+}
+
+// newHubAuth returns nil when there is no access token, meaning requests go out anonymously
+func (p *DockerHubProvider) newHubAuth(username, accessToken string) *hubAuth {
+	if accessToken == "" {
+		return nil
+	}
+	return &hubAuth{provider: p, username: username, accessToken: accessToken}
+}
+
+// token returns a valid session JWT, logging in again when the cached one is missing or stale
+func (a *hubAuth) token(ctx context.Context) (string, error) {
+	fingerprint := tokenFingerprint(a.accessToken)
+
+	hubSessionsMu.Lock()
+	session, ok := hubSessions[a.username]
+	hubSessionsMu.Unlock()
+
+	if ok && session.fingerprint == fingerprint && time.Now().Before(session.expiresAt.Add(-hubSessionRefreshMargin)) {
+		return session.token, nil
+	}
+
+	token, err := a.provider.login(ctx, a.username, a.accessToken)
+	if err != nil {
+		return "", err
+	}
+
+	hubSessionsMu.Lock()
+	hubSessions[a.username] = hubSession{
+		token:       token,
+		fingerprint: fingerprint,
+		expiresAt:   sessionExpiry(token),
+	}
+	hubSessionsMu.Unlock()
+
+	return token, nil
+}
+
+// invalidate drops the cached session so the next call logs in again
+func (a *hubAuth) invalidate() {
+	invalidateHubSession(a.username)
+}
+
+// invalidateHubSession removes any cached session for a Docker username
+func invalidateHubSession(username string) {
+	hubSessionsMu.Lock()
+	delete(hubSessions, username)
+	hubSessionsMu.Unlock()
+}
+
+// login exchanges a Docker username and personal access token for a session JWT. Only a
+// rejection (401/403) is reported as a credential failure; network problems and server errors
+// are ordinary errors so syncs retry them instead of asking the user for a new token.
+func (p *DockerHubProvider) login(ctx context.Context, username, accessToken string) (string, error) {
+	payload, err := json.Marshal(map[string]string{
+		"username": username,
+		"password": accessToken,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.apiURL+"/users/login", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("docker hub login failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		log.Printf("Docker Hub login rejected for %s: status=%d", username, resp.StatusCode)
+		return "", ErrInvalidDockerToken
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("docker hub login returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode login response: %w", err)
+	}
+	if result.Token == "" {
+		return "", errors.New("docker hub login returned no token")
+	}
+
+	return result.Token, nil
+}
+
+// sessionExpiry reads the exp claim of a session JWT without verifying its signature
+func sessionExpiry(token string) time.Time {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err == nil {
+		if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+			return exp.Time
+		}
+	}
+	return time.Now().Add(hubSessionFallbackTTL)
+}
+
+// sessionScopes reads the scope claim of a session JWT, which Docker Hub issues
+// as a space separated string for personal access tokens
+func sessionScopes(token string) []string {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return nil
+	}
+
+	switch scope := claims["scope"].(type) {
+	case string:
+		return strings.Fields(scope)
+	case []interface{}:
+		scopes := make([]string, 0, len(scope))
+		for _, s := range scope {
+			if str, ok := s.(string); ok {
+				scopes = append(scopes, str)
+			}
+		}
+		return scopes
+	}
+	return nil
+}
+
+func tokenFingerprint(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// isAuthError reports whether err was caused by rejected registry credentials
+func isAuthError(err error) bool {
+	return errors.Is(err, ErrInvalidDockerToken) || errors.Is(err, ErrDockerHubAuthFailed) || errors.Is(err, ErrRegistryAuthFailed)
+}
+
+// syncErrorMessage formats an error for DockerAccount.LastSyncError, tagging credential failures
+func syncErrorMessage(err error) string {
+	if isAuthError(err) {
+		return authSyncErrorPrefix + err.Error()
+	}
+	return err.Error()
+}
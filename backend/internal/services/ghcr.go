@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"docker-heatmap/internal/config"
+	"docker-heatmap/internal/database"
+	"docker-heatmap/internal/models"
+	"docker-heatmap/internal/utils"
+)
+
+var ErrGitHubPackagesAuthFailed = errors.New("github packages access was revoked or has expired")
+
+const (
+	githubAPIURL = "https://api.github.com"
+	// ghcrNamespacePrefix keeps GitHub package owners apart from registry namespaces of the same name
+	ghcrNamespacePrefix = "ghcr.io/"
+)
+
+// githubPackage is a container package from the GitHub Packages API
+type githubPackage struct {
+	Name  string `json:"name"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+}
+
+// githubPackageVersion is one published version (image manifest) of a container package
+type githubPackageVersion struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"` // Manifest digest
+	CreatedAt string `json:"created_at"`
+	Metadata  struct {
+		Container struct {
+			Tags []string `json:"tags"`
+		} `json:"container"`
+	} `json:"metadata"`
+}
+
+// syncGHCR records a push event for every container package version owned by the
+// account's user, provided they granted the read:packages scope
+func (s *DockerHubService) syncGHCR(ctx context.Context, state *syncState) error {
+	var user models.User
+	if err := database.DB.First(&user, state.account.UserID).Error; err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+	if !user.GHCREnabled {
+		return nil
+	}
+
+	token, err := utils.Decrypt(user.GitHubPackagesToken, user.GitHubPackagesTokenIV)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt github token: %w", err)
+	}
+
+	packages, truncated, err := fetchGitHubPages[githubPackage](ctx, githubAPIURL+"/user/packages?package_type=container&per_page=100", token)
+	if truncated {
+		state.markTruncated()
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Processing %d GitHub container packages for user %d", len(packages), user.ID)
+
+	for _, pkg := range packages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		versionsURL := fmt.Sprintf("%s/user/packages/container/%s/versions?per_page=100", githubAPIURL, url.PathEscape(pkg.Name))
+		versions, truncated, err := fetchGitHubPages[githubPackageVersion](ctx, versionsURL, token)
+		if truncated {
+			state.markTruncated()
+		}
+		if err != nil {
+			if errors.Is(err, ErrGitHubPackagesAuthFailed) {
+				return err
+			}
+			log.Printf("Failed to fetch versions of package %s: %v", pkg.Name, err)
+			continue
+		}
+
+		namespace := ghcrNamespacePrefix + strings.ToLower(pkg.Owner.Login)
+		repoKey := namespace + "/" + pkg.Name
+
+		for _, version := range versions {
+			createdAt, err := time.Parse(time.RFC3339, version.CreatedAt)
+			if err != nil {
+				log.Printf("Failed to parse package version date %s: %v", version.CreatedAt, err)
+				continue
+			}
+
+			tag := ""
+			if len(version.Metadata.Container.Tags) > 0 {
+				tag = version.Metadata.Container.Tags[0]
+			}
+
+			// Keyed without the tag: tags move between versions, the version itself is the push
+			event := newActivityEvent(state.account.ID, namespace, models.EventTypePush, createdAt, pkg.Name, tag, pushEventKey(repoKey, "", version.Name, createdAt), 1)
+			event.Source = ProviderGHCR
+			if s.createActivity(state.account, event) {
+				state.eventCreated()
+			}
+		}
+	}
+
+	return nil
+}
+
+// fetchGitHubPages follows the Link headers of a paginated GitHub API listing up to the page ceiling
+func fetchGitHubPages[T any](ctx context.Context, endpoint, token string) ([]T, bool, error) {
+	var items []T
+
+	for page := 0; endpoint != ""; page++ {
+		if page >= config.AppConfig.RegistryMaxPages {
+			return items, true, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return nil, false, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", "application/vnd.github+json")
+
+		resp, err := utils.HTTPClient.Do(req)
+		if err != nil {
+			return nil, false, err
+		}
+
+		var pageItems []T
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			err = ErrGitHubPackagesAuthFailed
+		case resp.StatusCode != http.StatusOK:
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			log.Printf("GitHub API error: status=%d body=%s", resp.StatusCode, string(body))
+			err = fmt.Errorf("github api returned status %d", resp.StatusCode)
+		default:
+			if decodeErr := json.NewDecoder(resp.Body).Decode(&pageItems); decodeErr != nil {
+				err = fmt.Errorf("failed to decode response: %w", decodeErr)
+			}
+		}
+		endpoint = nextPageURL(resp)
+		resp.Body.Close()
+
+		if err != nil {
+			return nil, false, err
+		}
+		items = append(items, pageItems...)
+	}
+
+	return items, false, nil
+}
@@ -6,20 +6,27 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"docker-heatmap/internal/config"
 	"docker-heatmap/internal/database"
 	"docker-heatmap/internal/models"
+	"docker-heatmap/internal/utils"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/github"
 )
 
 var (
-	ErrGitHubAuthFailed = errors.New("github authentication failed")
-	ErrUserNotFound     = errors.New("user not found")
+	ErrGitHubAuthFailed     = errors.New("github authentication failed")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrGitHubUserMismatch   = errors.New("github account does not match the signed-in user")
+	ErrPackagesScopeMissing = errors.New("read:packages scope was not granted")
 )
 
+// githubPackagesScope is requested on top of the login scopes to read ghcr.io packages
+const githubPackagesScope = "read:packages"
+
 type GitHubUser struct {
 	ID        int64  `json:"id"`
 	Login     string `json:"login"`
@@ -49,6 +56,70 @@ func (s *GitHubAuthService) GetAuthURL(state string) string {
 	return s.oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOnline)
 }
 
+// GetPackagesAuthURL returns an authorization URL that additionally requests read:packages
+func (s *GitHubAuthService) GetPackagesAuthURL(state string) string {
+	upgraded := *s.oauthConfig
+	upgraded.Scopes = append(append([]string{}, s.oauthConfig.Scopes...), githubPackagesScope)
+	return upgraded.AuthCodeURL(state, oauth2.AccessTypeOnline)
+}
+
+// EnablePackages exchanges a read:packages authorization code and stores the encrypted
+// token on the user, after checking it was issued for the same GitHub account
+func (s *GitHubAuthService) EnablePackages(ctx context.Context, userID uint, code string) error {
+	user, err := GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	token, err := s.oauthConfig.Exchange(ctx, code)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrGitHubAuthFailed, err)
+	}
+
+	scopes, _ := token.Extra("scope").(string)
+	if !hasScope(scopes, githubPackagesScope) {
+		return ErrPackagesScopeMissing
+	}
+
+	githubUser, err := s.fetchGitHubUser(ctx, token.AccessToken)
+	if err != nil {
+		return err
+	}
+	if githubUser.ID != user.GitHubID {
+		return ErrGitHubUserMismatch
+	}
+
+	encryptedToken, iv, err := utils.Encrypt(token.AccessToken)
+	if err != nil {
+		return fmt.Errorf("encryption failed: %w", err)
+	}
+
+	return database.DB.Model(user).Updates(map[string]interface{}{
+		"ghcr_enabled":             true,
+		"github_packages_token":    encryptedToken,
+		"github_packages_token_iv": iv,
+	}).Error
+}
+
+// DisablePackages forgets the user's read:packages token. Already recorded events are kept.
+func (s *GitHubAuthService) DisablePackages(userID uint) error {
+	return database.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"ghcr_enabled":             false,
+		"github_packages_token":    "",
+		"github_packages_token_iv": "",
+	}).Error
+}
+
+// hasScope reports whether a comma or space separated OAuth scope list contains scope
+func hasScope(scopes, scope string) bool {
+	for _, granted := range strings.FieldsFunc(scopes, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
 // ExchangeCode exchanges the authorization code for access token and fetches user data
 func (s *GitHubAuthService) ExchangeCode(ctx context.Context, code string) (*models.User, error) {
 	// Exchange code for token
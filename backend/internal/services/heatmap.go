@@ -32,6 +32,8 @@ type SVGOptions struct {
 	HideLabels  bool   // Hide month/day labels
 	FontFamily  string // Custom font family
 	CustomTitle string // Custom title instead of default
+	IncludeOrgs bool   // Include organization namespaces
+	Namespace   string // Only show this namespace
 
 	// Custom colors (when theme is "custom")
 	BgColor      string   // Background color
@@ -276,6 +278,11 @@ func (s *HeatmapService) GenerateSVGWithOptions(dockerUsername string, opts SVGO
 	if opts.CellRadius < 0 {
 		opts.CellRadius = 2
 	}
+	if opts.Theme == "" {
+		if account, err := s.dockerService.GetDockerAccountByUsername(dockerUsername); err == nil {
+			opts.Theme = account.DefaultTheme
+		}
+	}
 	if opts.Theme == "" {
 		opts.Theme = "github"
 	}
@@ -308,7 +315,10 @@ func (s *HeatmapService) GenerateSVGWithOptions(dockerUsername string, opts SVGO
 	}
 
 	// Get activity data
-	activities, err := s.dockerService.GetActivitySummary(dockerUsername, opts.Days)
+	activities, err := s.dockerService.GetActivitySummary(dockerUsername, opts.Days, ActivityFilter{
+		IncludeOrgs: opts.IncludeOrgs,
+		Namespace:   opts.Namespace,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -510,6 +520,12 @@ func ParseSVGOptionsFromQuery(params map[string]string) SVGOptions {
 	if v, ok := params["title"]; ok {
 		opts.CustomTitle = v
 	}
+	if v, ok := params["orgs"]; ok && (v == "true" || v == "1") {
+		opts.IncludeOrgs = true
+	}
+	if v, ok := params["namespace"]; ok {
+		opts.Namespace = v
+	}
 
 	// Custom colors support
 	if v, ok := params["bg_color"]; ok {
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"docker-heatmap/internal/database"
+	"docker-heatmap/internal/models"
+	"docker-heatmap/internal/utils"
+)
+
+var (
+	ErrNamespaceNotAccessible = errors.New("access token cannot see this namespace")
+	ErrNamespaceExists        = errors.New("namespace already added")
+	ErrNamespaceNotFound      = errors.New("namespace not found")
+	ErrNamespaceIsOwn         = errors.New("the account's own namespace is always synced")
+)
+
+// GetNamespaces returns the extra namespaces configured for an account
+func (s *DockerHubService) GetNamespaces(accountID uint) ([]models.DockerNamespace, error) {
+	var namespaces []models.DockerNamespace
+	err := database.DB.Where("docker_account_id = ?", accountID).Order("name").Find(&namespaces).Error
+	return namespaces, err
+}
+
+// AddNamespace adds an organization namespace to sync after checking the account's token can see it
+func (s *DockerHubService) AddNamespace(ctx context.Context, account *models.DockerAccount, name string) (*models.DockerNamespace, error) {
+	if name == account.DockerUsername {
+		return nil, ErrNamespaceIsOwn
+	}
+
+	provider, err := GetProvider(account.Provider)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := provider.(NamespaceLister)
+	if !ok {
+		return nil, ErrNamespacesNotSupported
+	}
+
+	var count int64
+	database.DB.Model(&models.DockerNamespace{}).Where("docker_account_id = ? AND name = ?", account.ID, name).Count(&count)
+	if count > 0 {
+		return nil, ErrNamespaceExists
+	}
+
+	token, err := utils.Decrypt(account.EncryptedToken, account.TokenIV)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt token: %w", err)
+	}
+
+	orgs, err := lister.ListNamespaces(ctx, accountCredentials(account, token))
+	if err != nil {
+		return nil, err
+	}
+
+	member := false
+	for _, org := range orgs {
+		if org == name {
+			member = true
+			break
+		}
+	}
+	if !member {
+		return nil, ErrNamespaceNotAccessible
+	}
+
+	namespace := models.DockerNamespace{
+		DockerAccountID: account.ID,
+		Name:            name,
+	}
+	if err := database.DB.Create(&namespace).Error; err != nil {
+		return nil, fmt.Errorf("failed to add namespace: %w", err)
+	}
+
+	log.Printf("Added namespace %s to docker account ID=%d", name, account.ID)
+	return &namespace, nil
+}
+
+// RemoveNamespace stops syncing a namespace. Activity already recorded for it is kept.
+func (s *DockerHubService) RemoveNamespace(accountID uint, name string) error {
+	result := database.DB.Where("docker_account_id = ? AND name = ?", accountID, name).Delete(&models.DockerNamespace{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNamespaceNotFound
+	}
+
+	// Re-adding the namespace later should start from a full scan
+	if err := database.DB.Where("docker_account_id = ? AND namespace = ?", accountID, name).Delete(&models.RepositoryCursor{}).Error; err != nil {
+		log.Printf("Failed to clear cursors of namespace %s for account %d: %v", name, accountID, err)
+	}
+	return nil
+}
+
+// accountNamespaces returns every namespace synced for an account, its own first
+func (s *DockerHubService) accountNamespaces(account *models.DockerAccount) []string {
+	namespaces := []string{account.DockerUsername}
+
+	var extra []string
+	if err := database.DB.Model(&models.DockerNamespace{}).Where("docker_account_id = ?", account.ID).Order("name").Pluck("name", &extra).Error; err != nil {
+		log.Printf("Failed to load namespaces for account %d: %v", account.ID, err)
+	}
+
+	return append(namespaces, extra...)
+}
+
+// ownsNamespace reports whether a namespace is the account's own or one of its configured extras
+func (s *DockerHubService) ownsNamespace(account *models.DockerAccount, namespace string) bool {
+	for _, ns := range s.accountNamespaces(account) {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// qualifiedRepo names a repository for event identity. Repositories in the account's
+// own namespace keep their bare name so identities recorded before namespaces stay stable.
+func qualifiedRepo(account *models.DockerAccount, namespace, repo string) string {
+	if namespace == account.DockerUsername {
+		return repo
+	}
+	return namespace + "/" + repo
+}
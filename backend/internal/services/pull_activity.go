@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"docker-heatmap/internal/database"
+	"docker-heatmap/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// recordPullCount snapshots a repository's pull_count and turns the growth since the
+// previous snapshot into a pull event dated at capture time (returns true if an event was written).
+// The first snapshot of a repository only establishes the baseline, and an unchanged count is
+// not stored again, so snapshots only accumulate while a repository is being pulled.
+func (s *DockerHubService) recordPullCount(account *models.DockerAccount, namespace, repo string, pullCount int64, capturedAt time.Time) bool {
+	recorded := false
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var previous models.RepositoryPullSnapshot
+		prevErr := tx.Where("docker_account_id = ? AND namespace = ? AND repository = ?", account.ID, namespace, repo).
+			Order("captured_at DESC").
+			First(&previous).Error
+		if prevErr != nil && !errors.Is(prevErr, gorm.ErrRecordNotFound) {
+			return prevErr
+		}
+		if prevErr == nil && previous.PullCount == pullCount {
+			return nil
+		}
+
+		snapshot := models.RepositoryPullSnapshot{
+			DockerAccountID: account.ID,
+			Namespace:       namespace,
+			Repository:      repo,
+			PullCount:       pullCount,
+			CapturedAt:      capturedAt,
+		}
+		if err := tx.Create(&snapshot).Error; err != nil {
+			return err
+		}
+
+		if prevErr != nil {
+			return nil
+		}
+
+		// Counters can drop when Docker Hub recalculates them; only growth is activity
+		delta := pullCount - previous.PullCount
+		if delta <= 0 {
+			return nil
+		}
+
+		// Keyed by snapshot so each observed delta is stored exactly once
+		key := activityEventKey("pull_count", qualifiedRepo(account, namespace, repo), strconv.FormatUint(uint64(snapshot.ID), 10))
+		event := newActivityEvent(account.ID, namespace, models.EventTypePull, capturedAt, repo, "", key, int(delta))
+		event.Source = account.Provider
+		created, err := recordActivity(tx, &event)
+		recorded = created
+		return err
+	})
+
+	if err != nil {
+		log.Printf("Failed to record pull count for %s/%s: %v", namespace, repo, err)
+		return false
+	}
+
+	return recorded
+}
+
+// recordPullDay records that a tag was pulled on the given day (returns true if newly recorded).
+// Docker Hub only exposes the most recent pull, so each day is recorded once no matter how often it is seen.
+// The pulls themselves are already counted by the pull_count deltas; this only marks the day as active.
+func (s *DockerHubService) recordPullDay(account *models.DockerAccount, namespace, repo, tag string, pulledAt time.Time) bool {
+	key := activityEventKey("pull_day", qualifiedRepo(account, namespace, repo), tag, pulledAt.UTC().Format("2006-01-02"))
+	return s.createActivity(account, newActivityEvent(account.ID, namespace, models.EventTypePullDay, pulledAt, repo, tag, key, 1))
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"docker-heatmap/internal/models"
+)
+
+var (
+	ErrUnknownProvider          = errors.New("unknown registry provider")
+	ErrRegistryAuthFailed       = errors.New("registry authentication failed")
+	ErrNamespacesNotSupported   = errors.New("registry provider does not support extra namespaces")
+	ErrRegistryNamespaceMissing = errors.New("registry namespace not found")
+	ErrTokenOwnerMismatch       = errors.New("access token does not belong to this username")
+	ErrRegistryUserNotFound     = errors.New("registry user not found")
+	ErrInvalidCredentialFormat  = errors.New("invalid credentials")
+)
+
+// Registry provider names stored in DockerAccount.Provider
+const (
+	ProviderDockerHub = "dockerhub"
+	ProviderQuay      = "quay"
+	ProviderOCI       = "oci"
+	// ProviderGHCR marks GitHub Container Registry events; it is synced through the
+	// user's GitHub login rather than connected as an account provider
+	ProviderGHCR = "ghcr"
+)
+
+// RegistryCredentials identifies an account to a registry.
+// An empty AccessToken means requests are made anonymously.
+type RegistryCredentials struct {
+	RegistryURL string // Base URL for self-hosted registries, empty for hosted ones
+	Username    string
+	AccessToken string
+}
+
+// TokenIdentity is who the registry says the credentials authenticate as
+type TokenIdentity struct {
+	Username string   // Empty when the registry accepted the request without authenticating
+	Scopes   []string // Permissions granted to the token, when the registry reports them
+}
+
+// RegistryRepository is a repository as reported by a registry
+type RegistryRepository struct {
+	Namespace   string
+	Name        string
+	LastUpdated string // Raw timestamp, parsed with the provider's ParseTimestamp
+	PullCount   int64  // Cumulative pulls, or -1 when the registry does not report them
+}
+
+// RegistryTag is a tag as reported by a registry
+type RegistryTag struct {
+	Name       string
+	Digest     string
+	PushedAt   string // Raw timestamp of the latest push
+	LastPulled string // Raw timestamp of the latest pull, empty when unknown
+}
+
+// RegistryProvider is implemented by every container registry activity can be synced from.
+// The list methods report whether the provider's page ceiling cut the listing short.
+type RegistryProvider interface {
+	// Name returns the provider identifier stored on DockerAccount
+	Name() string
+	// CheckCredentialFormat rejects usernames and tokens the registry could never accept,
+	// before any request is made
+	CheckCredentialFormat(username, accessToken string) error
+	// ValidateCredentials checks that the credentials are accepted by the registry
+	// and reports the identity they authenticate as
+	ValidateCredentials(ctx context.Context, creds RegistryCredentials) (*TokenIdentity, error)
+	// ListRepositories lists every repository in a namespace
+	ListRepositories(ctx context.Context, creds RegistryCredentials, namespace string) ([]RegistryRepository, bool, error)
+	// ListTags lists the tags of a repository. When since is non-zero, providers that list
+	// newest first may stop paging once tags were pushed before it.
+	ListTags(ctx context.Context, creds RegistryCredentials, namespace, repo string, since time.Time) ([]RegistryTag, bool, error)
+	// ParseTimestamp parses the raw timestamps this provider returns
+	ParseTimestamp(value string) (time.Time, error)
+}
+
+// NamespaceLister is implemented by providers that can list the extra namespaces
+// (organizations) the credentials have access to
+type NamespaceLister interface {
+	ListNamespaces(ctx context.Context, creds RegistryCredentials) ([]string, error)
+}
+
+// ChangeMarker is implemented by providers whose repository listing carries no last_updated.
+// RepositoryMarker returns a cheap value that changes whenever any tag of the repository
+// changes, so unchanged repositories can still skip their tag scan.
+type ChangeMarker interface {
+	RepositoryMarker(ctx context.Context, creds RegistryCredentials, namespace, repo string) (string, error)
+}
+
+// SelfHostedProvider is implemented by providers whose registry URL is chosen per account
+type SelfHostedProvider interface {
+	// NormalizeRegistryURL validates a user-supplied registry URL and returns its canonical form
+	NormalizeRegistryURL(raw string) (string, error)
+}
+
+// registryProviders maps provider names to constructors. New registries only need an entry here.
+var registryProviders = map[string]func() RegistryProvider{
+	ProviderDockerHub: func() RegistryProvider { return NewDockerHubProvider() },
+	ProviderQuay:      func() RegistryProvider { return NewQuayProvider() },
+	ProviderOCI:       func() RegistryProvider { return NewOCIProvider() },
+}
+
+// GetProvider returns the provider registered under name. An empty name means Docker Hub.
+func GetProvider(name string) (RegistryProvider, error) {
+	if name == "" {
+		name = ProviderDockerHub
+	}
+
+	newProvider, ok := registryProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return newProvider(), nil
+}
+
+// ProviderNames returns the names of all registered providers
+func ProviderNames() []string {
+	names := make([]string, 0, len(registryProviders))
+	for name := range registryProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// accountCredentials builds the provider credentials for an account from its decrypted token
+func accountCredentials(account *models.DockerAccount, accessToken string) RegistryCredentials {
+	return RegistryCredentials{
+		RegistryURL: account.RegistryURL,
+		Username:    account.DockerUsername,
+		AccessToken: accessToken,
+	}
+}
+
+// verifyTokenOwner checks that the credentials authenticate as the username being connected
+func verifyTokenOwner(identity *TokenIdentity, username string) error {
+	if identity == nil || identity.Username == "" || !strings.EqualFold(identity.Username, username) {
+		return ErrTokenOwnerMismatch
+	}
+	return nil
+}
+
+// tokenOwnership reports whether the registry vouched for the username. Self-hosted registries
+// may not authenticate anyone; their accounts are accepted unverified, scoped to the registry.
+func tokenOwnership(provider RegistryProvider, identity *TokenIdentity, username string) (bool, error) {
+	if _, selfHosted := provider.(SelfHostedProvider); selfHosted && identity != nil && identity.Username == "" {
+		return false, nil
+	}
+	if err := verifyTokenOwner(identity, username); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+
+	"docker-heatmap/internal/config"
+)
+
+// parseDockerTime parses Docker Hub's date format which includes microseconds
+func parseDockerTime(dateStr string) (time.Time, error) {
+	// Docker Hub uses ISO 8601 format with microseconds: 2026-01-17T08:19:30.340959Z
+	formats := []string{
+		"2006-01-02T15:04:05.999999Z",
+		"2006-01-02T15:04:05.999999-07:00",
+		time.RFC3339Nano,
+		time.RFC3339,
+	}
+
+	for _, format := range formats {
+		if t, err := time.Parse(format, dateStr); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unable to parse date: %s", dateStr)
+}
+
+// Docker Hub usernames: 4-30 chars, alphanumeric with allowed special chars
+var dockerHubUsernameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{2,29}$`)
+
+// DockerHubProvider implements RegistryProvider for Docker Hub
+type DockerHubProvider struct {
+	apiURL   string
+	maxPages int
+	client   *DockerHubClient
+}
+
+func NewDockerHubProvider() *DockerHubProvider {
+	return &DockerHubProvider{
+		apiURL:   config.AppConfig.DockerHubAPIURL,
+		maxPages: config.AppConfig.RegistryMaxPages,
+		client:   SharedDockerHubClient(),
+	}
+}
+
+// DockerHubRepository represents a repository from Docker Hub API
+type DockerHubRepository struct {
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace"`
+	Description string `json:"description"`
+	LastUpdated string `json:"last_updated"` // ISO string
+	PullCount   int64  `json:"pull_count"`
+	StarCount   int    `json:"star_count"`
+	IsPrivate   bool   `json:"is_private"`
+}
+
+// DockerHubTag represents a tag from Docker Hub API
+type DockerHubTag struct {
+	Name          string `json:"name"`
+	LastUpdated   string `json:"last_updated"`
+	TagLastPushed string `json:"tag_last_pushed"`
+	TagLastPulled string `json:"tag_last_pulled"`
+	Digest        string `json:"digest"`
+}
+
+// dockerHubOrg is an organization from Docker Hub's /user/orgs listing
+type dockerHubOrg struct {
+	OrgName string `json:"orgname"`
+}
+
+// dockerHubPage is the envelope Docker Hub wraps around paginated listings
+type dockerHubPage[T any] struct {
+	Count   int    `json:"count"`
+	Next    string `json:"next"`
+	Results []T    `json:"results"`
+}
+
+func (p *DockerHubProvider) Name() string {
+	return ProviderDockerHub
+}
+
+// CheckCredentialFormat accepts Docker Hub usernames with a password or personal access token
+func (p *DockerHubProvider) CheckCredentialFormat(username, accessToken string) error {
+	if !dockerHubUsernameRegex.MatchString(username) {
+		return fmt.Errorf("%w: invalid Docker username format", ErrInvalidCredentialFormat)
+	}
+	// Docker PATs are typically 36+ chars
+	if len(accessToken) < 10 || len(accessToken) > 500 {
+		return fmt.Errorf("%w: invalid access token length", ErrInvalidCredentialFormat)
+	}
+	return nil
+}
+
+// ValidateCredentials checks that the username exists, logs in with the access token and
+// asks Docker Hub which user the resulting session belongs to
+func (p *DockerHubProvider) ValidateCredentials(ctx context.Context, creds RegistryCredentials) (*TokenIdentity, error) {
+	if err := p.validateUsername(ctx, creds.Username); err != nil {
+		return nil, err
+	}
+
+	auth := p.newHubAuth(creds.Username, creds.AccessToken)
+	if auth == nil {
+		return nil, ErrInvalidDockerToken
+	}
+
+	invalidateHubSession(creds.Username)
+	session, err := auth.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var user struct {
+		Username string `json:"username"`
+	}
+	if err := getDockerHubJSON(ctx, p.client, p.apiURL+"/user/", auth, &user); err != nil {
+		return nil, fmt.Errorf("failed to fetch token owner: %w", err)
+	}
+
+	return &TokenIdentity{Username: user.Username, Scopes: sessionScopes(session)}, nil
+}
+
+func (p *DockerHubProvider) ListRepositories(ctx context.Context, creds RegistryCredentials, namespace string) ([]RegistryRepository, bool, error) {
+	repos, truncated, err := p.FetchRepositories(ctx, creds.Username, namespace, creds.AccessToken)
+	if err != nil {
+		return nil, false, err
+	}
+
+	result := make([]RegistryRepository, 0, len(repos))
+	for _, repo := range repos {
+		result = append(result, RegistryRepository{
+			Namespace:   namespace,
+			Name:        repo.Name,
+			LastUpdated: repo.LastUpdated,
+			PullCount:   repo.PullCount,
+		})
+	}
+	return result, truncated, nil
+}
+
+func (p *DockerHubProvider) ListTags(ctx context.Context, creds RegistryCredentials, namespace, repo string, since time.Time) ([]RegistryTag, bool, error) {
+	tags, truncated, err := p.FetchTags(ctx, creds.Username, namespace, repo, creds.AccessToken, since)
+	if err != nil {
+		return nil, false, err
+	}
+
+	result := make([]RegistryTag, 0, len(tags))
+	for _, tag := range tags {
+		result = append(result, RegistryTag{
+			Name:       tag.Name,
+			Digest:     tag.Digest,
+			PushedAt:   tag.TagLastPushed,
+			LastPulled: tag.TagLastPulled,
+		})
+	}
+	return result, truncated, nil
+}
+
+func (p *DockerHubProvider) ParseTimestamp(value string) (time.Time, error) {
+	return parseDockerTime(value)
+}
+
+// ListNamespaces lists the organizations the token's user belongs to
+func (p *DockerHubProvider) ListNamespaces(ctx context.Context, creds RegistryCredentials) ([]string, error) {
+	auth := p.newHubAuth(creds.Username, creds.AccessToken)
+	if auth == nil {
+		return nil, ErrInvalidDockerToken
+	}
+
+	url := fmt.Sprintf("%s/user/orgs/?page_size=100", p.apiURL)
+	orgs, _, err := fetchAllPages[dockerHubOrg](ctx, p.client, url, auth, p.maxPages, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch organizations: %w", err)
+	}
+
+	names := make([]string, 0, len(orgs))
+	for _, org := range orgs {
+		names = append(names, org.OrgName)
+	}
+	return names, nil
+}
+
+// validateUsername checks if a Docker Hub username exists
+func (p *DockerHubProvider) validateUsername(ctx context.Context, username string) error {
+	url := fmt.Sprintf("%s/users/%s", p.apiURL, username)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		log.Printf("Docker Hub API request failed: %v", err)
+		return fmt.Errorf("docker hub user lookup failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	log.Printf("Docker Hub user lookup: status=%d for user=%s", resp.StatusCode, username)
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrRegistryUserNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("docker hub user lookup returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// FetchRepositories fetches all repositories in a namespace, authenticating as username.
+// The returned bool reports whether the page ceiling cut the listing short.
+func (p *DockerHubProvider) FetchRepositories(ctx context.Context, username, namespace, accessToken string) ([]DockerHubRepository, bool, error) {
+	url := fmt.Sprintf("%s/repositories/%s?page_size=100", p.apiURL, namespace)
+
+	repos, truncated, err := fetchAllPages[DockerHubRepository](ctx, p.client, url, p.newHubAuth(username, accessToken), p.maxPages, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch repositories: %w", err)
+	}
+
+	log.Printf("Fetched %d repositories for %s (truncated=%t)", len(repos), namespace, truncated)
+	return repos, truncated, nil
+}
+
+// FetchTags fetches the tags of a repository, most recently updated first. With a non-zero
+// since, paging stops after the first page reaching tags not updated since then.
+// The returned bool reports whether the page ceiling cut the listing short.
+func (p *DockerHubProvider) FetchTags(ctx context.Context, username, namespace, repoName, accessToken string, since time.Time) ([]DockerHubTag, bool, error) {
+	url := fmt.Sprintf("%s/repositories/%s/%s/tags?page_size=100&ordering=last_updated", p.apiURL, namespace, repoName)
+
+	var olderThanSince func(DockerHubTag) bool
+	if !since.IsZero() {
+		olderThanSince = func(tag DockerHubTag) bool {
+			updated, err := parseDockerTime(tag.LastUpdated)
+			return err == nil && updated.Before(since)
+		}
+	}
+
+	return fetchAllPages[DockerHubTag](ctx, p.client, url, p.newHubAuth(username, accessToken), p.maxPages, olderThanSince)
+}
+
+// FetchTag fetches a single tag of a repository
+func (p *DockerHubProvider) FetchTag(ctx context.Context, username, namespace, repoName, tagName, accessToken string) (*DockerHubTag, error) {
+	url := fmt.Sprintf("%s/repositories/%s/%s/tags/%s", p.apiURL, namespace, repoName, tagName)
+
+	var tag DockerHubTag
+	if err := getDockerHubJSON(ctx, p.client, url, p.newHubAuth(username, accessToken), &tag); err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// fetchAllPages follows Docker Hub's `next` links until the listing is exhausted,
+// maxPages pages have been read or the context is cancelled. If stop is given, paging
+// also ends after the first page containing an item it matches.
+func fetchAllPages[T any](ctx context.Context, client *DockerHubClient, url string, auth *hubAuth, maxPages int, stop func(T) bool) ([]T, bool, error) {
+	var all []T
+
+	for page := 0; url != ""; page++ {
+		if page >= maxPages {
+			return all, true, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+
+		var result dockerHubPage[T]
+		if err := getDockerHubJSON(ctx, client, url, auth, &result); err != nil {
+			return nil, false, err
+		}
+
+		all = append(all, result.Results...)
+		url = result.Next
+
+		if stop != nil {
+			for _, item := range result.Results {
+				if stop(item) {
+					return all, false, nil
+				}
+			}
+		}
+	}
+
+	return all, false, nil
+}
+
+// getDockerHubJSON performs a GET against Docker Hub and decodes the JSON body.
+// With credentials, a 401 discards the cached session and retries once with a fresh login.
+func getDockerHubJSON(ctx context.Context, client *DockerHubClient, url string, auth *hubAuth, out interface{}) error {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/json")
+		if auth != nil {
+			token, err := auth.token(ctx)
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && auth != nil {
+			resp.Body.Close()
+			auth.invalidate()
+			if attempt == 0 {
+				continue
+			}
+			return fmt.Errorf("%w: session rejected by docker hub", ErrDockerHubAuthFailed)
+		}
+
+		err = decodeDockerHubResponse(resp, out)
+		resp.Body.Close()
+		return err
+	}
+}
+
+func decodeDockerHubResponse(resp *http.Response, out interface{}) error {
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		log.Printf("Docker Hub API error: status=%d body=%s", resp.StatusCode, string(body))
+		return fmt.Errorf("docker hub api returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
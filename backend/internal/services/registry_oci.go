@@ -0,0 +1,562 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"docker-heatmap/internal/config"
+	"docker-heatmap/internal/utils"
+)
+
+var (
+	ErrInvalidRegistryURL     = errors.New("registry URL must be an absolute http(s) URL")
+	ErrRegistryHostNotAllowed = errors.New("registry host is not allowed")
+)
+
+// ociHTTPClient refuses internal addresses (or hosts outside OCI_ALLOWED_HOSTS), since
+// registry URLs and token realms are chosen by users
+var ociHTTPClient = sync.OnceValue(func() *http.Client {
+	return utils.NewRestrictedHTTPClient(config.AppConfig.OCIAllowedHosts)
+})
+
+const (
+	ociPageSize = 100
+	// ociMaxBodySize bounds manifest and config blob reads
+	ociMaxBodySize = 4 << 20
+	// ociCreatedAnnotation lets a manifest carry its creation time without fetching the config blob
+	ociCreatedAnnotation = "org.opencontainers.image.created"
+)
+
+// Manifest media types accepted from the registry, single-platform types first
+var ociManifestAccept = strings.Join([]string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+}, ", ")
+
+// OCIProvider implements RegistryProvider for self-hosted registries speaking the
+// OCI Distribution API (registry:2, Harbor, ...). The registry URL comes from the account.
+// The catalog is not namespaced, so every repository the credentials can see is
+// listed under the account's own namespace with its full path as the name.
+type OCIProvider struct {
+	maxPages int
+	client   *http.Client
+
+	// Bearer tokens issued by the registry's token service, keyed by realm and scope
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+func NewOCIProvider() *OCIProvider {
+	return &OCIProvider{
+		maxPages: config.AppConfig.RegistryMaxPages,
+		client:   ociHTTPClient(),
+		tokens:   make(map[string]string),
+	}
+}
+
+// ociManifest covers both image manifests and image indexes
+type ociManifest struct {
+	MediaType string `json:"mediaType"`
+	Config    struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Manifests []struct {
+		Digest string `json:"digest"`
+	} `json:"manifests"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+func (p *OCIProvider) Name() string {
+	return ProviderOCI
+}
+
+// CheckCredentialFormat only enforces what basic auth requires: self-hosted registries have
+// their own naming rules (Harbor robots are named robot$project+name) and any password length
+func (p *OCIProvider) CheckCredentialFormat(username, accessToken string) error {
+	if username == "" || len(username) > 255 || strings.ContainsRune(username, ':') || strings.IndexFunc(username, unicode.IsSpace) >= 0 || strings.IndexFunc(username, unicode.IsControl) >= 0 {
+		return fmt.Errorf("%w: invalid registry username format", ErrInvalidCredentialFormat)
+	}
+	if accessToken == "" || len(accessToken) > 4096 {
+		return fmt.Errorf("%w: invalid access token length", ErrInvalidCredentialFormat)
+	}
+	return nil
+}
+
+// NormalizeRegistryURL checks that raw is an http(s) base URL and strips any trailing path separator
+func (p *OCIProvider) NormalizeRegistryURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", ErrInvalidRegistryURL
+	}
+	if u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return "", ErrInvalidRegistryURL
+	}
+	if !registryHostAllowed(u) {
+		return "", ErrRegistryHostNotAllowed
+	}
+	return strings.TrimRight(u.String(), "/"), nil
+}
+
+// registryHostAllowed rejects what can be ruled out before dialing: hosts outside the allowlist,
+// and internal IP literals. Names resolving to internal addresses are refused by the client.
+func registryHostAllowed(u *url.URL) bool {
+	if allowed := config.AppConfig.OCIAllowedHosts; len(allowed) > 0 {
+		for _, host := range allowed {
+			if strings.EqualFold(host, u.Hostname()) || strings.EqualFold(host, u.Host) {
+				return true
+			}
+		}
+		return false
+	}
+	if ip, err := netip.ParseAddr(u.Hostname()); err == nil {
+		return utils.IsPublicAddr(ip)
+	}
+	return !strings.EqualFold(u.Hostname(), "localhost")
+}
+
+// ValidateCredentials checks the API version endpoint. The registry only vouches for the
+// username when it demanded authentication and refuses a wrong password for it; otherwise
+// the identity is anonymous and the account stays unverified.
+func (p *OCIProvider) ValidateCredentials(ctx context.Context, creds RegistryCredentials) (*TokenIdentity, error) {
+	if creds.RegistryURL == "" {
+		return nil, ErrInvalidRegistryURL
+	}
+
+	probe, err := http.NewRequestWithContext(ctx, "GET", creds.RegistryURL+"/v2/", nil)
+	if err != nil {
+		return nil, err
+	}
+	anonymous, err := p.client.Do(probe)
+	if err != nil {
+		return nil, fmt.Errorf("registry unreachable: %w", err)
+	}
+	anonymous.Body.Close()
+	if anonymous.StatusCode == http.StatusOK {
+		return &TokenIdentity{}, nil
+	}
+
+	resp, err := p.get(ctx, creds, creds.RegistryURL+"/v2/", "registry:catalog:*", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry returned status %d", resp.StatusCode)
+	}
+
+	// Some token services hand out tokens to anyone; such a registry vouches for nobody
+	decoy, err := utils.GenerateRandomString(16)
+	if err != nil {
+		return nil, err
+	}
+	fresh := &OCIProvider{maxPages: p.maxPages, client: p.client, tokens: make(map[string]string)}
+	resp, err = fresh.get(ctx, RegistryCredentials{RegistryURL: creds.RegistryURL, Username: creds.Username, AccessToken: decoy}, creds.RegistryURL+"/v2/", "registry:catalog:*", "")
+	switch {
+	case isAuthError(err):
+		return &TokenIdentity{Username: creds.Username}, nil
+	case err != nil:
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return &TokenIdentity{}, nil
+	}
+	return nil, fmt.Errorf("registry returned status %d", resp.StatusCode)
+}
+
+func (p *OCIProvider) ListRepositories(ctx context.Context, creds RegistryCredentials, namespace string) ([]RegistryRepository, bool, error) {
+	var repos []RegistryRepository
+	next := fmt.Sprintf("%s/v2/_catalog?n=%d", creds.RegistryURL, ociPageSize)
+
+	for page := 0; next != ""; page++ {
+		if page >= p.maxPages {
+			return repos, true, nil
+		}
+
+		var result struct {
+			Repositories []string `json:"repositories"`
+		}
+		var err error
+		next, err = p.getJSON(ctx, creds, next, "registry:catalog:*", &result)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to fetch catalog: %w", err)
+		}
+
+		for _, name := range result.Repositories {
+			repos = append(repos, RegistryRepository{
+				Namespace: namespace,
+				Name:      name,
+				PullCount: -1, // The Distribution API has no pull statistics
+			})
+		}
+	}
+
+	return repos, false, nil
+}
+
+// ListTags lists a repository's tags and resolves each one's digest and image creation time.
+// The tag list is alphabetical, so since cannot shorten it.
+func (p *OCIProvider) ListTags(ctx context.Context, creds RegistryCredentials, namespace, repo string, since time.Time) ([]RegistryTag, bool, error) {
+	names, truncated, err := p.listTagNames(ctx, creds, repo)
+	if err != nil {
+		return nil, false, err
+	}
+
+	tags := make([]RegistryTag, 0, len(names))
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+
+		digest, created, err := p.resolveTag(ctx, creds, repo, name)
+		if err != nil {
+			if isAuthError(err) {
+				return nil, false, err
+			}
+			// Keep the tag so at least its digest history is complete next time
+			log.Printf("Failed to resolve %s:%s on %s: %v", repo, name, creds.RegistryURL, err)
+		}
+		tags = append(tags, RegistryTag{Name: name, Digest: digest, PushedAt: created})
+	}
+
+	return tags, truncated, nil
+}
+
+// listTagNames pages through a repository's tag list
+func (p *OCIProvider) listTagNames(ctx context.Context, creds RegistryCredentials, repo string) ([]string, bool, error) {
+	scope := "repository:" + repo + ":pull"
+	next := fmt.Sprintf("%s/v2/%s/tags/list?n=%d", creds.RegistryURL, repo, ociPageSize)
+
+	var names []string
+	for page := 0; next != ""; page++ {
+		if page >= p.maxPages {
+			return names, true, nil
+		}
+
+		var result struct {
+			Tags []string `json:"tags"`
+		}
+		var err error
+		next, err = p.getJSON(ctx, creds, next, scope, &result)
+		if err != nil {
+			return nil, false, err
+		}
+		names = append(names, result.Tags...)
+	}
+	return names, false, nil
+}
+
+// RepositoryMarker hashes every tag with its manifest digest. The digests come from HEAD
+// requests, so an unchanged repository costs one request per tag instead of the manifest
+// and config fetches of a full scan, and a re-pushed tag still changes the marker.
+func (p *OCIProvider) RepositoryMarker(ctx context.Context, creds RegistryCredentials, namespace, repo string) (string, error) {
+	names, _, err := p.listTagNames(ctx, creds, repo)
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(names)
+
+	scope := "repository:" + repo + ":pull"
+	hash := sha256.New()
+	for _, name := range names {
+		manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", creds.RegistryURL, repo, name)
+		resp, err := p.do(ctx, creds, http.MethodHead, manifestURL, scope, ociManifestAccept)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+
+		digest := resp.Header.Get("Docker-Content-Digest")
+		if resp.StatusCode != http.StatusOK || digest == "" {
+			return "", fmt.Errorf("no digest for %s:%s (status %d)", repo, name, resp.StatusCode)
+		}
+		fmt.Fprintf(hash, "%s@%s\n", name, digest)
+	}
+	return "oci:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ParseTimestamp parses the RFC 3339 created times found in image configs and annotations
+func (p *OCIProvider) ParseTimestamp(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// resolveTag returns the manifest digest of a tag and the creation time of its image.
+// For multi-platform indexes the first listed manifest stands in for the image.
+func (p *OCIProvider) resolveTag(ctx context.Context, creds RegistryCredentials, repo, tag string) (string, string, error) {
+	scope := "repository:" + repo + ":pull"
+
+	manifest, digest, err := p.fetchManifest(ctx, creds, repo, tag, scope)
+	if err != nil {
+		return "", "", err
+	}
+	if created := manifest.Annotations[ociCreatedAnnotation]; created != "" {
+		return digest, created, nil
+	}
+
+	if manifest.Config.Digest == "" && len(manifest.Manifests) > 0 {
+		manifest, _, err = p.fetchManifest(ctx, creds, repo, manifest.Manifests[0].Digest, scope)
+		if err != nil {
+			return digest, "", err
+		}
+		if created := manifest.Annotations[ociCreatedAnnotation]; created != "" {
+			return digest, created, nil
+		}
+	}
+	if manifest.Config.Digest == "" {
+		return digest, "", nil
+	}
+
+	var imageConfig struct {
+		Created string `json:"created"`
+	}
+	blobURL := fmt.Sprintf("%s/v2/%s/blobs/%s", creds.RegistryURL, repo, manifest.Config.Digest)
+	if _, err := p.getJSON(ctx, creds, blobURL, scope, &imageConfig); err != nil {
+		return digest, "", fmt.Errorf("failed to fetch image config: %w", err)
+	}
+
+	return digest, imageConfig.Created, nil
+}
+
+// fetchManifest fetches a manifest by tag or digest along with its content digest
+func (p *OCIProvider) fetchManifest(ctx context.Context, creds RegistryCredentials, repo, reference, scope string) (*ociManifest, string, error) {
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", creds.RegistryURL, repo, reference)
+
+	resp, err := p.get(ctx, creds, manifestURL, scope, ociManifestAccept)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("manifest request returned status %d", resp.StatusCode)
+	}
+
+	var manifest ociManifest
+	if err := json.NewDecoder(io.LimitReader(resp.Body, ociMaxBodySize)).Decode(&manifest); err != nil {
+		return nil, "", fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" && strings.HasPrefix(reference, "sha256:") {
+		digest = reference
+	}
+	return &manifest, digest, nil
+}
+
+// getJSON decodes a successful response into out and returns the next page URL from the Link header, if any
+func (p *OCIProvider) getJSON(ctx context.Context, creds RegistryCredentials, endpoint, scope string, out interface{}) (string, error) {
+	resp, err := p.get(ctx, creds, endpoint, scope, "application/json")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", ErrRegistryNamespaceMissing
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		log.Printf("Registry API error: url=%s status=%d body=%s", endpoint, resp.StatusCode, string(body))
+		return "", fmt.Errorf("registry returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, ociMaxBodySize)).Decode(out); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nextPageURL(resp), nil
+}
+
+// get performs an authenticated GET, answering the registry's WWW-Authenticate
+// challenge with basic auth or a bearer token from its token service.
+// A 401 that survives the challenge is reported as ErrRegistryAuthFailed.
+func (p *OCIProvider) get(ctx context.Context, creds RegistryCredentials, endpoint, scope, accept string) (*http.Response, error) {
+	return p.do(ctx, creds, http.MethodGet, endpoint, scope, accept)
+}
+
+// do is get for any method
+func (p *OCIProvider) do(ctx context.Context, creds RegistryCredentials, method, endpoint, scope, accept string) (*http.Response, error) {
+	send := func(authorization string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		return p.client.Do(req)
+	}
+
+	authorization := p.cachedAuthorization(creds, scope)
+	resp, err := send(authorization)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+
+	authorization, err = p.answerChallenge(ctx, creds, challenge, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err = send(authorization)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: registry returned status %d", ErrRegistryAuthFailed, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// cachedAuthorization returns a previously issued bearer token for the scope, if any
+func (p *OCIProvider) cachedAuthorization(creds RegistryCredentials, scope string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if token, ok := p.tokens[creds.RegistryURL+"|"+scope]; ok {
+		return "Bearer " + token
+	}
+	return ""
+}
+
+// answerChallenge builds the Authorization header for a WWW-Authenticate challenge
+func (p *OCIProvider) answerChallenge(ctx context.Context, creds RegistryCredentials, challenge, scope string) (string, error) {
+	scheme, params := parseAuthChallenge(challenge)
+
+	switch scheme {
+	case "basic":
+		if creds.AccessToken == "" {
+			return "", ErrRegistryAuthFailed
+		}
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.SetBasicAuth(creds.Username, creds.AccessToken)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+		token, err := p.fetchBearerToken(ctx, creds, params, scope)
+		if err != nil {
+			return "", err
+		}
+		p.mu.Lock()
+		p.tokens[creds.RegistryURL+"|"+scope] = token
+		p.mu.Unlock()
+		return "Bearer " + token, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported auth challenge %q", ErrRegistryAuthFailed, challenge)
+	}
+}
+
+// fetchBearerToken requests a token from the realm named in a Bearer challenge
+func (p *OCIProvider) fetchBearerToken(ctx context.Context, creds RegistryCredentials, params map[string]string, scope string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || (realm.Scheme != "http" && realm.Scheme != "https") {
+		return "", fmt.Errorf("%w: invalid token realm", ErrRegistryAuthFailed)
+	}
+
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	if challengeScope := params["scope"]; challengeScope != "" {
+		scope = challengeScope
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if creds.AccessToken != "" {
+		req.SetBasicAuth(creds.Username, creds.AccessToken)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token service unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return "", fmt.Errorf("%w: token service returned status %d", ErrRegistryAuthFailed, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("token service returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if result.Token != "" {
+		return result.Token, nil
+	}
+	if result.AccessToken != "" {
+		return result.AccessToken, nil
+	}
+	return "", fmt.Errorf("%w: token service returned no token", ErrRegistryAuthFailed)
+}
+
+// parseAuthChallenge splits a WWW-Authenticate header into its lowercased scheme and parameters
+func parseAuthChallenge(header string) (string, map[string]string) {
+	params := make(map[string]string)
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key != "" {
+			params[strings.ToLower(strings.TrimSpace(key))] = value
+		}
+	}
+
+	return strings.ToLower(scheme), params
+}
+
+// nextPageURL resolves the rel="next" target of a Link header against the request URL
+func nextPageURL(resp *http.Response) string {
+	for _, link := range resp.Header.Values("Link") {
+		target, params, ok := strings.Cut(link, ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		ref, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			return ""
+		}
+		return resp.Request.URL.ResolveReference(ref).String()
+	}
+	return ""
+}
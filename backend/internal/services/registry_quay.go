@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"docker-heatmap/internal/config"
+	"docker-heatmap/internal/utils"
+)
+
+// Quay usernames, including robot accounts (namespace+robot)
+var quayUsernameRegex = regexp.MustCompile(`^[a-z0-9_][a-z0-9_.-]{1,254}(\+[a-z0-9_]{1,254})?$`)
+
+// QuayProvider implements RegistryProvider for Quay.io using an OAuth application token
+type QuayProvider struct {
+	apiURL   string
+	maxPages int
+	client   *http.Client
+}
+
+func NewQuayProvider() *QuayProvider {
+	return &QuayProvider{
+		apiURL:   config.AppConfig.QuayAPIURL,
+		maxPages: config.AppConfig.RegistryMaxPages,
+		client:   utils.HTTPClient,
+	}
+}
+
+// quayRepository represents a repository from the Quay API
+type quayRepository struct {
+	Namespace    string `json:"namespace"`
+	Name         string `json:"name"`
+	LastModified *int64 `json:"last_modified"` // Unix seconds, null for empty repositories
+}
+
+// quayTag represents a tag from the Quay API
+type quayTag struct {
+	Name           string `json:"name"`
+	ManifestDigest string `json:"manifest_digest"`
+	StartTS        int64  `json:"start_ts"`      // Unix seconds the tag started pointing at this manifest
+	LastModified   string `json:"last_modified"` // RFC 1123 with numeric zone
+}
+
+func (p *QuayProvider) Name() string {
+	return ProviderQuay
+}
+
+// CheckCredentialFormat accepts Quay users and robot accounts with an OAuth or robot token
+func (p *QuayProvider) CheckCredentialFormat(username, accessToken string) error {
+	if !quayUsernameRegex.MatchString(username) {
+		return fmt.Errorf("%w: invalid Quay username format", ErrInvalidCredentialFormat)
+	}
+	if len(accessToken) < 10 || len(accessToken) > 500 {
+		return fmt.Errorf("%w: invalid access token length", ErrInvalidCredentialFormat)
+	}
+	return nil
+}
+
+// ValidateCredentials checks that the token is accepted by Quay and returns the user it was issued to
+func (p *QuayProvider) ValidateCredentials(ctx context.Context, creds RegistryCredentials) (*TokenIdentity, error) {
+	if creds.AccessToken == "" {
+		return nil, ErrRegistryAuthFailed
+	}
+
+	var user struct {
+		Username string `json:"username"`
+	}
+	if err := p.getJSON(ctx, creds, p.apiURL+"/user/", &user); err != nil {
+		return nil, err
+	}
+	return &TokenIdentity{Username: user.Username}, nil
+}
+
+func (p *QuayProvider) ListRepositories(ctx context.Context, creds RegistryCredentials, namespace string) ([]RegistryRepository, bool, error) {
+	var repos []RegistryRepository
+	nextPage := ""
+
+	for page := 0; ; page++ {
+		if page >= p.maxPages {
+			return repos, true, nil
+		}
+
+		query := url.Values{}
+		query.Set("namespace", namespace)
+		query.Set("last_modified", "true")
+		if nextPage != "" {
+			query.Set("next_page", nextPage)
+		}
+
+		var result struct {
+			Repositories []quayRepository `json:"repositories"`
+			NextPage     string           `json:"next_page"`
+		}
+		if err := p.getJSON(ctx, creds, p.apiURL+"/repository?"+query.Encode(), &result); err != nil {
+			return nil, false, fmt.Errorf("failed to fetch repositories: %w", err)
+		}
+
+		for _, repo := range result.Repositories {
+			lastUpdated := ""
+			if repo.LastModified != nil {
+				lastUpdated = strconv.FormatInt(*repo.LastModified, 10)
+			}
+			repos = append(repos, RegistryRepository{
+				Namespace:   namespace,
+				Name:        repo.Name,
+				LastUpdated: lastUpdated,
+				PullCount:   -1, // Quay does not expose cumulative pull counts
+			})
+		}
+
+		if result.NextPage == "" {
+			return repos, false, nil
+		}
+		nextPage = result.NextPage
+	}
+}
+
+// ListTags pages through active tags, which Quay returns most recently started first
+func (p *QuayProvider) ListTags(ctx context.Context, creds RegistryCredentials, namespace, repo string, since time.Time) ([]RegistryTag, bool, error) {
+	var tags []RegistryTag
+
+	for page := 1; ; page++ {
+		if page > p.maxPages {
+			return tags, true, nil
+		}
+
+		endpoint := fmt.Sprintf("%s/repository/%s/%s/tag/?limit=100&onlyActiveTags=true&page=%d",
+			p.apiURL, url.PathEscape(namespace), url.PathEscape(repo), page)
+
+		var result struct {
+			Tags          []quayTag `json:"tags"`
+			HasAdditional bool      `json:"has_additional"`
+		}
+		if err := p.getJSON(ctx, creds, endpoint, &result); err != nil {
+			return nil, false, err
+		}
+
+		for _, tag := range result.Tags {
+			pushedAt := tag.LastModified
+			if tag.StartTS > 0 {
+				pushedAt = strconv.FormatInt(tag.StartTS, 10)
+			}
+			tags = append(tags, RegistryTag{
+				Name:     tag.Name,
+				Digest:   tag.ManifestDigest,
+				PushedAt: pushedAt,
+			})
+		}
+
+		if !result.HasAdditional {
+			return tags, false, nil
+		}
+		if !since.IsZero() && len(result.Tags) > 0 && result.Tags[len(result.Tags)-1].StartTS < since.Unix() {
+			return tags, false, nil
+		}
+	}
+}
+
+// ParseTimestamp accepts Unix seconds as well as Quay's RFC 1123 and RFC 3339 dates
+func (p *QuayProvider) ParseTimestamp(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	for _, format := range []string{time.RFC1123Z, time.RFC1123, time.RFC3339Nano} {
+		if t, err := time.Parse(format, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unable to parse date: %s", value)
+}
+
+// getJSON performs a GET against the Quay API and decodes the JSON body
+func (p *QuayProvider) getJSON(ctx context.Context, creds RegistryCredentials, endpoint string, out interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if creds.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+creds.AccessToken)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w: quay returned status %d", ErrRegistryAuthFailed, resp.StatusCode)
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrRegistryNamespaceMissing
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		log.Printf("Quay API error: status=%d body=%s", resp.StatusCode, string(body))
+		return fmt.Errorf("quay api returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"docker-heatmap/internal/database"
+	"docker-heatmap/internal/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// repoFullScanInterval forces a complete tag listing now and then even for unchanged
+// repositories, since tag_last_pulled moves without touching last_updated
+const repoFullScanInterval = 24 * time.Hour
+
+// loadRepositoryCursors returns the cursors of a namespace keyed by repository name
+func loadRepositoryCursors(accountID uint, namespace string) map[string]*models.RepositoryCursor {
+	var cursors []models.RepositoryCursor
+	if err := database.DB.Where("docker_account_id = ? AND namespace = ?", accountID, namespace).Find(&cursors).Error; err != nil {
+		log.Printf("Failed to load repository cursors for account %d namespace %s: %v", accountID, namespace, err)
+	}
+
+	byRepo := make(map[string]*models.RepositoryCursor, len(cursors))
+	for i := range cursors {
+		byRepo[cursors[i].Repository] = &cursors[i]
+	}
+	return byRepo
+}
+
+// repositoryUnchanged reports whether the tag scan of a repository can be skipped entirely
+func repositoryUnchanged(cursor *models.RepositoryCursor, lastUpdated string, now time.Time) bool {
+	return cursor != nil &&
+		lastUpdated != "" &&
+		cursor.LastUpdated == lastUpdated &&
+		now.Sub(cursor.FullScanAt) < repoFullScanInterval
+}
+
+// repositoryMarker returns the value the cursor compares to detect changes: last_updated, or a
+// marker derived by the provider when its listing has none. Empty means a full tag scan.
+func repositoryMarker(ctx context.Context, state *syncState, namespace string, repo RegistryRepository) string {
+	if repo.LastUpdated != "" {
+		return repo.LastUpdated
+	}
+	markers, ok := state.provider.(ChangeMarker)
+	if !ok {
+		return ""
+	}
+	marker, err := markers.RepositoryMarker(ctx, state.creds, namespace, repo.Name)
+	if err != nil {
+		log.Printf("Failed to derive change marker for %s/%s: %v", namespace, repo.Name, err)
+		return ""
+	}
+	return marker
+}
+
+// tagCutoff returns the push time below which tag paging may stop, zero for a full scan
+func tagCutoff(cursor *models.RepositoryCursor, now time.Time) time.Time {
+	if cursor == nil || cursor.LastTagPushAt == nil || now.Sub(cursor.FullScanAt) >= repoFullScanInterval {
+		return time.Time{}
+	}
+	return *cursor.LastTagPushAt
+}
+
+// saveRepositoryCursor advances a repository's cursor after a successful tag scan
+func saveRepositoryCursor(cursor *models.RepositoryCursor) {
+	err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "docker_account_id"}, {Name: "namespace"}, {Name: "repository"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at", "last_updated", "last_tag_push_at", "full_scan_at"}),
+	}).Create(cursor).Error
+	if err != nil {
+		log.Printf("Failed to save cursor for %s/%s: %v", cursor.Namespace, cursor.Repository, err)
+	}
+}
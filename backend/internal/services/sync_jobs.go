@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"docker-heatmap/internal/database"
+	"docker-heatmap/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// syncJobMaxAttempts is how often a failing job runs before it is dead-lettered
+const syncJobMaxAttempts = 5
+
+// syncJobPriorities puts user-initiated syncs ahead of scheduled ones
+var syncJobPriorities = map[models.SyncTrigger]int{
+	models.SyncTriggerCron:    models.SyncJobPriorityScheduled,
+	models.SyncTriggerWebhook: models.SyncJobPriorityWebhook,
+	models.SyncTriggerConnect: models.SyncJobPriorityConnect,
+	models.SyncTriggerManual:  models.SyncJobPriorityManual,
+}
+
+// EnqueueSync queues a sync of the account for the worker pool. An account has at most
+// one pending job: a new request merges into it, raising its priority and bringing it forward.
+func (s *DockerHubService) EnqueueSync(accountID uint, trigger models.SyncTrigger) (*models.SyncJob, error) {
+	job := models.SyncJob{
+		DockerAccountID: accountID,
+		Trigger:         trigger,
+		Priority:        syncJobPriorities[trigger],
+		Status:          models.SyncJobStatusPending,
+		RunAt:           time.Now(),
+		MaxAttempts:     syncJobMaxAttempts,
+	}
+
+	err := database.DB.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "docker_account_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "status = 'pending'"}}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"trigger":  gorm.Expr("CASE WHEN EXCLUDED.priority > sync_jobs.priority THEN EXCLUDED.trigger ELSE sync_jobs.trigger END"),
+			"priority": gorm.Expr("GREATEST(sync_jobs.priority, EXCLUDED.priority)"),
+			"run_at":   gorm.Expr("LEAST(sync_jobs.run_at, EXCLUDED.run_at)"),
+		}),
+	}).Create(&job).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue sync: %w", err)
+	}
+
+	log.Printf("Queued sync job %d for account %d (trigger=%s)", job.ID, accountID, trigger)
+	return &job, nil
+}
+
+// IsPermanentSyncError reports whether retrying a failed sync cannot help: revoked
+// credentials and namespaces or accounts that no longer exist fail the same way every time
+func IsPermanentSyncError(err error) bool {
+	return isAuthError(err) || errors.Is(err, ErrGitHubPackagesAuthFailed) || errors.Is(err, ErrRegistryNamespaceMissing) ||
+		errors.Is(err, ErrUnknownProvider) || errors.Is(err, gorm.ErrRecordNotFound)
+}
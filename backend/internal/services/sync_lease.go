@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"docker-heatmap/internal/database"
+	"docker-heatmap/internal/models"
+	"docker-heatmap/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+var ErrSyncLeaseLost = errors.New("sync lease lost to another worker")
+
+const (
+	// syncLeaseTTL is how long a lease survives without a heartbeat, e.g. after a crash
+	syncLeaseTTL = 90 * time.Second
+	// syncLeaseHeartbeat renews the lease well before it can expire
+	syncLeaseHeartbeat = syncLeaseTTL / 3
+	// syncCancelPollInterval is how often the lease holder checks for a cancel request
+	syncCancelPollInterval = 5 * time.Second
+)
+
+// InstanceID identifies this process in sync lease and job owner IDs
+var InstanceID = func() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}()
+
+// syncLease is a time-limited claim on an account's sync, held in docker_accounts so
+// it is shared by every replica and reclaimed automatically once it expires
+type syncLease struct {
+	accountID uint
+	owner     string
+}
+
+// acquireSyncLease atomically claims the account's sync unless a live lease exists.
+// Expiry is computed with the database clock so replicas never compare their own clocks.
+func acquireSyncLease(accountID uint) (*syncLease, error) {
+	nonce, err := utils.GenerateRandomString(8)
+	if err != nil {
+		return nil, err
+	}
+	lease := &syncLease{accountID: accountID, owner: InstanceID + "-" + nonce}
+
+	result := database.DB.Model(&models.DockerAccount{}).
+		Where("id = ? AND (sync_lease_expires_at IS NULL OR sync_lease_expires_at < NOW())", accountID).
+		Updates(map[string]interface{}{
+			"sync_lease_owner":         lease.owner,
+			"sync_lease_expires_at":    leaseExpiry(),
+			"sync_cancel_requested_at": nil, // Meant for an earlier sync
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to acquire sync lease: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrSyncInProgress
+	}
+	return lease, nil
+}
+
+// renew extends the lease; it fails once another owner has taken it over
+func (l *syncLease) renew() error {
+	result := database.DB.Model(&models.DockerAccount{}).
+		Where("id = ? AND sync_lease_owner = ?", l.accountID, l.owner).
+		Update("sync_lease_expires_at", leaseExpiry())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSyncLeaseLost
+	}
+	return nil
+}
+
+// cancelRequested reports whether a cancel request was stored for the lease holder's sync
+func (l *syncLease) cancelRequested() (bool, error) {
+	var count int64
+	err := database.DB.Model(&models.DockerAccount{}).
+		Where("id = ? AND sync_lease_owner = ? AND sync_cancel_requested_at IS NOT NULL", l.accountID, l.owner).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// keepAlive renews the lease until ctx ends and watches for cancel requests from other
+// replicas. It calls abort with ErrSyncLeaseLost if the lease cannot be kept, or with
+// ErrSyncCancelled once a cancel was requested. Transient database errors are retried
+// until the lease would have expired.
+func (l *syncLease) keepAlive(ctx context.Context, abort context.CancelCauseFunc) {
+	heartbeat := time.NewTicker(syncLeaseHeartbeat)
+	defer heartbeat.Stop()
+	cancelPoll := time.NewTicker(syncCancelPollInterval)
+	defer cancelPoll.Stop()
+
+	renewedAt := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-cancelPoll.C:
+			requested, err := l.cancelRequested()
+			if err != nil {
+				log.Printf("Failed to check cancel request for account %d: %v", l.accountID, err)
+				continue
+			}
+			if requested {
+				log.Printf("Cancel requested for sync of account %d", l.accountID)
+				abort(ErrSyncCancelled)
+				return
+			}
+		case <-heartbeat.C:
+			err := l.renew()
+			if err == nil {
+				renewedAt = time.Now()
+				continue
+			}
+
+			log.Printf("Failed to renew sync lease for account %d: %v", l.accountID, err)
+			if errors.Is(err, ErrSyncLeaseLost) || time.Since(renewedAt) >= syncLeaseTTL-syncLeaseHeartbeat {
+				abort(ErrSyncLeaseLost)
+				return
+			}
+		}
+	}
+}
+
+// requestSyncCancel stores a cancel request for the account's live lease, which its holder
+// picks up on its next poll. It fails with ErrNoSyncRunning when no lease is held.
+func requestSyncCancel(accountID uint) error {
+	result := database.DB.Model(&models.DockerAccount{}).
+		Where("id = ? AND sync_lease_expires_at > NOW()", accountID).
+		Update("sync_cancel_requested_at", gorm.Expr("NOW()"))
+	if result.Error != nil {
+		return fmt.Errorf("failed to request sync cancellation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNoSyncRunning
+	}
+	return nil
+}
+
+// release gives the lease up if this owner still holds it
+func (l *syncLease) release() {
+	err := database.DB.Model(&models.DockerAccount{}).
+		Where("id = ? AND sync_lease_owner = ?", l.accountID, l.owner).
+		Updates(map[string]interface{}{
+			"sync_lease_owner":         "",
+			"sync_lease_expires_at":    nil,
+			"sync_cancel_requested_at": nil,
+		}).Error
+	if err != nil {
+		log.Printf("Failed to release sync lease for account %d: %v", l.accountID, err)
+	}
+}
+
+func leaseExpiry() interface{} {
+	return gorm.Expr("NOW() + make_interval(secs => ?)", syncLeaseTTL.Seconds())
+}
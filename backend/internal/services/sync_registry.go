@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+)
+
+var (
+	ErrSyncInProgress   = errors.New("sync already in progress")
+	ErrSyncCancelled    = errors.New("sync cancelled")
+	ErrNoSyncRunning    = errors.New("no sync in progress")
+	ErrSyncShuttingDown = errors.New("server is shutting down")
+	ErrSyncInterrupted  = errors.New("sync interrupted by server shutdown")
+	ErrSyncPartial      = errors.New("sync finished with errors")
+)
+
+// Cancel functions of the syncs running in this process, keyed by account ID. Once
+// draining, no new syncs are registered.
+var (
+	runningSyncs   = make(map[uint]context.CancelCauseFunc)
+	runningSyncsMu sync.Mutex
+	activeSyncs    sync.WaitGroup
+	draining       bool
+)
+
+// registerSync derives a cancellable context for an account's sync. The returned
+// release func must be called when the sync ends.
+func registerSync(ctx context.Context, accountID uint) (context.Context, func(), error) {
+	runningSyncsMu.Lock()
+	defer runningSyncsMu.Unlock()
+
+	if draining {
+		return nil, nil, ErrSyncShuttingDown
+	}
+	if _, running := runningSyncs[accountID]; running {
+		return nil, nil, ErrSyncInProgress
+	}
+
+	syncCtx, cancel := context.WithCancelCause(ctx)
+	runningSyncs[accountID] = cancel
+	activeSyncs.Add(1)
+
+	release := func() {
+		runningSyncsMu.Lock()
+		delete(runningSyncs, accountID)
+		runningSyncsMu.Unlock()
+		cancel(nil)
+		activeSyncs.Done()
+	}
+	return syncCtx, release, nil
+}
+
+// CancelSync stops the account's sync. A sync running in this process is cancelled right away;
+// one running on another replica is asked to stop through its lease.
+func (s *DockerHubService) CancelSync(accountID uint) error {
+	runningSyncsMu.Lock()
+	cancel, running := runningSyncs[accountID]
+	runningSyncsMu.Unlock()
+
+	if !running {
+		return requestSyncCancel(accountID)
+	}
+	cancel(ErrSyncCancelled)
+	return nil
+}
+
+// syncCancelled reports whether ctx was stopped through CancelSync
+func syncCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrSyncCancelled)
+}
+
+// syncInterrupted reports whether ctx was stopped because the server shut down
+func syncInterrupted(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrSyncInterrupted)
+}
+
+// DrainSyncs stops new syncs from starting and waits for the running ones until ctx ends.
+// Syncs still running then are interrupted, and DrainSyncs waits for them to record their
+// interrupted state before returning.
+func DrainSyncs(ctx context.Context) {
+	runningSyncsMu.Lock()
+	draining = true
+	count := len(runningSyncs)
+	runningSyncsMu.Unlock()
+
+	if count > 0 {
+		log.Printf("Waiting for %d running syncs to finish...", count)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		activeSyncs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+
+	runningSyncsMu.Lock()
+	log.Printf("Grace period over, interrupting %d running syncs", len(runningSyncs))
+	for _, cancel := range runningSyncs {
+		cancel(ErrSyncInterrupted)
+	}
+	runningSyncsMu.Unlock()
+
+	<-done
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"docker-heatmap/internal/database"
+	"docker-heatmap/internal/models"
+)
+
+// startSyncRun persists a running SyncRun. Failing to record history never blocks a sync.
+func startSyncRun(accountID uint, trigger models.SyncTrigger) *models.SyncRun {
+	run := &models.SyncRun{
+		DockerAccountID: accountID,
+		Trigger:         trigger,
+		Status:          models.SyncStatusRunning,
+		StartedAt:       time.Now(),
+	}
+	if err := database.DB.Create(run).Error; err != nil {
+		log.Printf("Failed to record sync run for account %d: %v", accountID, err)
+	}
+	return run
+}
+
+// orphanedSyncRunError is recorded on runs whose worker died before finishing them
+const orphanedSyncRunError = "sync worker stopped before the run finished"
+
+// InterruptOrphanedSyncRuns closes runs still marked running although no live lease covers
+// their account, i.e. the worker holding it crashed. Webhook runs are finished on the spot
+// and never hold a lease, so they are left alone.
+func InterruptOrphanedSyncRuns() (int64, error) {
+	result := database.DB.Model(&models.SyncRun{}).
+		Where("status = ? AND trigger <> ?", models.SyncStatusRunning, models.SyncTriggerWebhook).
+		Where("docker_account_id IN (?)", database.DB.Model(&models.DockerAccount{}).Select("id").Where("sync_lease_expires_at IS NULL OR sync_lease_expires_at < NOW()")).
+		Updates(orphanedSyncRunUpdates())
+	return result.RowsAffected, result.Error
+}
+
+// interruptPreviousSyncRuns closes the account's runs left open by an earlier worker. Only
+// called with the account's lease held, so none of them can still be in progress.
+func interruptPreviousSyncRuns(accountID uint) {
+	err := database.DB.Model(&models.SyncRun{}).
+		Where("docker_account_id = ? AND status = ? AND trigger <> ?", accountID, models.SyncStatusRunning, models.SyncTriggerWebhook).
+		Updates(orphanedSyncRunUpdates()).Error
+	if err != nil {
+		log.Printf("Failed to close orphaned sync runs of account %d: %v", accountID, err)
+	}
+}
+
+func orphanedSyncRunUpdates() map[string]interface{} {
+	return map[string]interface{}{
+		"status":      models.SyncStatusInterrupted,
+		"finished_at": time.Now(),
+		"error":       orphanedSyncRunError,
+	}
+}
+
+// finishSyncRun stores the outcome and statistics of a run. aborted marks runs that
+// stopped early (credentials, cancellation) as opposed to finishing with some failures.
+func finishSyncRun(run *models.SyncRun, state *syncState, syncErr error, aborted bool) {
+	if run.ID == 0 {
+		return
+	}
+
+	state.mu.Lock()
+	run.ReposScanned = state.reposScanned
+	run.TagsScanned = state.tagsScanned
+	run.EventsCreated = state.eventsCreated
+	run.Truncated = state.truncated
+	runErrors := state.runErrors
+	state.mu.Unlock()
+
+	now := time.Now()
+	run.FinishedAt = &now
+	switch {
+	case errors.Is(syncErr, ErrSyncCancelled):
+		run.Status = models.SyncStatusCancelled
+	case errors.Is(syncErr, ErrSyncInterrupted):
+		run.Status = models.SyncStatusInterrupted
+	case aborted:
+		run.Status = models.SyncStatusFailed
+	case syncErr != nil || len(runErrors) > 0:
+		run.Status = models.SyncStatusPartial
+	default:
+		run.Status = models.SyncStatusSuccess
+	}
+	if syncErr != nil {
+		run.Error = syncErr.Error()
+	}
+
+	if err := database.DB.Save(run).Error; err != nil {
+		log.Printf("Failed to update sync run %d: %v", run.ID, err)
+		return
+	}
+
+	for i := range runErrors {
+		runErrors[i].SyncRunID = run.ID
+	}
+	if len(runErrors) > 0 {
+		if err := database.DB.Create(&runErrors).Error; err != nil {
+			log.Printf("Failed to record errors of sync run %d: %v", run.ID, err)
+		}
+	}
+}
+
+// recordWebhookRun logs a webhook delivery as a single-tag run so it shows up in sync history
+func recordWebhookRun(accountID uint, namespace, repo string, created bool, webhookErr error) {
+	state := &syncState{reposScanned: 1, tagsScanned: 1}
+	if created {
+		state.eventsCreated = 1
+	}
+	if webhookErr != nil {
+		state.runErrors = append(state.runErrors, models.SyncRunError{Namespace: namespace, Repository: repo, Message: webhookErr.Error()})
+	}
+	finishSyncRun(startSyncRun(accountID, models.SyncTriggerWebhook), state, webhookErr, webhookErr != nil)
+}
+
+// GetSyncRuns returns an account's most recent sync runs with their errors, newest first
+func (s *DockerHubService) GetSyncRuns(accountID uint, limit int) ([]models.SyncRun, error) {
+	var runs []models.SyncRun
+	err := database.DB.Preload("Errors").
+		Where("docker_account_id = ?", accountID).
+		Order("started_at DESC").
+		Limit(limit).
+		Find(&runs).Error
+	return runs, err
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"docker-heatmap/internal/config"
+	"docker-heatmap/internal/database"
+	"docker-heatmap/internal/models"
+)
+
+const (
+	// syncActivityWindow is how far back pushes and pulls are counted to estimate an account's activity
+	syncActivityWindow = 30 * 24 * time.Hour
+	// syncDormantWindow separates quiet accounts (daily syncs) from abandoned ones (weekly)
+	syncDormantWindow = 90 * 24 * time.Hour
+
+	quietSyncInterval   = 24 * time.Hour
+	dormantSyncInterval = MaxSyncIntervalHours * time.Hour
+)
+
+// adaptiveSyncInterval derives the time until an account's next scheduled sync from its recent
+// activity: every push, plus every day its images were pulled. Pulls are counted by day because
+// pull events are deltas between syncs, so their number grows with the sync rate itself.
+// Active accounts sync at least at their preferred interval and more often the busier they are,
+// down to SYNC_MIN_INTERVAL; accounts without recent activity back off to daily and then weekly syncs.
+func adaptiveSyncInterval(account *models.DockerAccount, now time.Time) time.Duration {
+	minInterval := config.AppConfig.SyncMinInterval
+	preferred := max(time.Duration(account.SyncIntervalHours)*time.Hour, minInterval)
+
+	windowStart := now.Add(-syncActivityWindow)
+	var stats struct {
+		Pushes       int64
+		PullDays     int64
+		LastActivity *time.Time
+	}
+	err := database.DB.Model(&models.ActivityEvent{}).
+		Select(`COALESCE(SUM(count) FILTER (WHERE event_type = ? AND event_date >= ?), 0) AS pushes,
+			COUNT(DISTINCT event_date::date) FILTER (WHERE event_type IN ? AND event_date >= ?) AS pull_days,
+			MAX(event_date) AS last_activity`,
+			models.EventTypePush, windowStart, []models.EventType{models.EventTypePull, models.EventTypePullDay}, windowStart).
+		Where("docker_account_id = ? AND event_type IN ?", account.ID, []models.EventType{models.EventTypePush, models.EventTypePull, models.EventTypePullDay}).
+		Scan(&stats).Error
+	if err != nil {
+		log.Printf("Failed to load activity for account %d: %v", account.ID, err)
+		return preferred
+	}
+
+	switch {
+	case stats.Pushes+stats.PullDays > 0:
+		// Sync twice per average gap between activity, so new activity waits half a gap at most
+		interval := syncActivityWindow / time.Duration(stats.Pushes+stats.PullDays) / 2
+		return max(min(interval, preferred), minInterval)
+	case stats.LastActivity != nil && now.Sub(*stats.LastActivity) < syncDormantWindow:
+		return max(preferred, quietSyncInterval)
+	default:
+		return max(preferred, dormantSyncInterval)
+	}
+}
+
+// nextSyncAt is when the worker should next sync an account that finished syncing at now
+func nextSyncAt(account *models.DockerAccount, now time.Time) time.Time {
+	return now.Add(adaptiveSyncInterval(account, now))
+}
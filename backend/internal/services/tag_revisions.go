@@ -0,0 +1,45 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"docker-heatmap/internal/database"
+	"docker-heatmap/internal/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// recordTagRevision stores the digest a tag pointed to at a given push, ignoring revisions already known
+func (s *DockerHubService) recordTagRevision(account *models.DockerAccount, namespace, repo, tag, digest string, pushedAt time.Time) {
+	if digest == "" {
+		return
+	}
+
+	revision := models.TagRevision{
+		DockerAccountID: account.ID,
+		Namespace:       namespace,
+		Repository:      repo,
+		Tag:             tag,
+		Digest:          digest,
+		PushedAt:        pushedAt.UTC().Truncate(time.Second),
+	}
+
+	err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&revision).Error
+	if err != nil {
+		log.Printf("Failed to record tag revision %s/%s:%s@%s: %v", namespace, repo, tag, digest, err)
+	}
+}
+
+// GetTagRevisions returns a repository's tag/digest timeline, newest push first.
+// An empty tag returns revisions of every tag.
+func (s *DockerHubService) GetTagRevisions(accountID uint, namespace, repo, tag string, limit int) ([]models.TagRevision, error) {
+	query := database.DB.Where("docker_account_id = ? AND namespace = ? AND repository = ?", accountID, namespace, repo)
+	if tag != "" {
+		query = query.Where("tag = ?", tag)
+	}
+
+	var revisions []models.TagRevision
+	err := query.Order("pushed_at DESC, id DESC").Limit(limit).Find(&revisions).Error
+	return revisions, err
+}
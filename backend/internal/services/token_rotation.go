@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"docker-heatmap/internal/database"
+	"docker-heatmap/internal/models"
+	"docker-heatmap/internal/utils"
+)
+
+// UpdateToken replaces an account's access token in place, keeping its ID and activity history.
+// The new token must authenticate as the account's username.
+func (s *DockerHubService) UpdateToken(ctx context.Context, account *models.DockerAccount, accessToken string) error {
+	provider, err := GetProvider(account.Provider)
+	if err != nil {
+		return err
+	}
+
+	if err := provider.CheckCredentialFormat(account.DockerUsername, accessToken); err != nil {
+		return err
+	}
+
+	identity, err := provider.ValidateCredentials(ctx, accountCredentials(account, accessToken))
+	if err != nil {
+		return err
+	}
+	verified, err := tokenOwnership(provider, identity, account.DockerUsername)
+	if err != nil {
+		return err
+	}
+	// A verified account keeps requiring proof of ownership
+	if account.OwnerVerified && !verified {
+		return ErrTokenOwnerMismatch
+	}
+
+	encryptedToken, iv, err := utils.Encrypt(accessToken)
+	if err != nil {
+		return fmt.Errorf("encryption failed: %w", err)
+	}
+
+	updates := map[string]interface{}{
+		"encrypted_token": encryptedToken,
+		"token_iv":        iv,
+		"token_scopes":    strings.Join(identity.Scopes, " "),
+		"owner_verified":  verified,
+	}
+	// Errors caused by the old credentials no longer apply
+	if strings.HasPrefix(account.LastSyncError, authSyncErrorPrefix) {
+		updates["last_sync_error"] = ""
+	}
+
+	if err := database.DB.Model(account).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to store token: %w", err)
+	}
+
+	// Sessions issued for the old token must not be reused
+	invalidateHubSession(account.DockerUsername)
+
+	log.Printf("Access token updated for docker account ID=%d", account.ID)
+	return nil
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"docker-heatmap/internal/database"
+	"docker-heatmap/internal/models"
+	"docker-heatmap/internal/utils"
+)
+
+var (
+	ErrInvalidWebhookSecret  = errors.New("invalid webhook secret")
+	ErrInvalidWebhookPayload = errors.New("invalid webhook payload")
+)
+
+// webhookSecretLength is the length of generated webhook secrets
+const webhookSecretLength = 40
+
+// DockerHubWebhookPayload is the subset of Docker Hub's push webhook body we rely on
+type DockerHubWebhookPayload struct {
+	CallbackURL string `json:"callback_url"`
+	PushData    struct {
+		PushedAt int64  `json:"pushed_at"` // Unix seconds
+		Pusher   string `json:"pusher"`
+		Tag      string `json:"tag"`
+	} `json:"push_data"`
+	Repository struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+		RepoName  string `json:"repo_name"`
+	} `json:"repository"`
+}
+
+// Validate checks that the payload carries everything needed to record a push
+func (p *DockerHubWebhookPayload) Validate() error {
+	switch {
+	case p.Repository.Name == "" || p.Repository.Namespace == "":
+		return fmt.Errorf("%w: missing repository", ErrInvalidWebhookPayload)
+	case p.PushData.Tag == "":
+		return fmt.Errorf("%w: missing tag", ErrInvalidWebhookPayload)
+	case p.PushData.PushedAt <= 0:
+		return fmt.Errorf("%w: missing pushed_at", ErrInvalidWebhookPayload)
+	}
+	return nil
+}
+
+// RotateWebhookSecret generates a new webhook secret for the account, invalidating the old one.
+// Only a hash is stored, so the returned secret cannot be retrieved again.
+func (s *DockerHubService) RotateWebhookSecret(account *models.DockerAccount) (string, error) {
+	secret, err := utils.GenerateRandomString(webhookSecretLength)
+	if err != nil {
+		return "", err
+	}
+
+	if err := database.DB.Model(account).Update("webhook_secret_hash", hashWebhookSecret(secret)).Error; err != nil {
+		return "", fmt.Errorf("failed to store webhook secret: %w", err)
+	}
+
+	log.Printf("Rotated webhook secret for docker account ID=%d", account.ID)
+	return secret, nil
+}
+
+// HandlePushWebhook records the push described by a Docker Hub webhook for the account owning the secret
+func (s *DockerHubService) HandlePushWebhook(ctx context.Context, secret string, payload *DockerHubWebhookPayload) error {
+	if len(secret) != webhookSecretLength {
+		return ErrInvalidWebhookSecret
+	}
+
+	var account models.DockerAccount
+	if err := database.DB.Where("webhook_secret_hash = ?", hashWebhookSecret(secret)).First(&account).Error; err != nil {
+		return ErrInvalidWebhookSecret
+	}
+
+	if err := payload.Validate(); err != nil {
+		return err
+	}
+
+	if account.Provider != ProviderDockerHub {
+		return ErrInvalidWebhookSecret
+	}
+
+	// A paused account records nothing; the delivery is acknowledged so Docker Hub does not retry
+	if !account.IsActive {
+		log.Printf("Ignoring webhook for paused account %d", account.ID)
+		return nil
+	}
+
+	namespace := payload.Repository.Namespace
+	if !s.ownsNamespace(&account, namespace) {
+		return fmt.Errorf("%w: namespace %q is not synced for this account", ErrInvalidWebhookPayload, namespace)
+	}
+
+	repo, tagName := payload.Repository.Name, payload.PushData.Tag
+
+	// The event must carry the same identity a later sync computes from the tag, so without
+	// the lookup the push is left to a sync instead of being recorded under a different key
+	tag, err := s.lookupWebhookTag(ctx, &account, namespace, repo, tagName)
+	if err != nil {
+		log.Printf("Webhook tag lookup failed for %s/%s:%s, queueing a sync instead: %v", namespace, repo, tagName, err)
+		if _, err := s.EnqueueSync(account.ID, models.SyncTriggerWebhook); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	digest := tag.Digest
+	pushedAt := time.Unix(payload.PushData.PushedAt, 0).UTC()
+	if parsed, err := parseDockerTime(tag.TagLastPushed); err == nil {
+		pushedAt = parsed
+	}
+
+	s.recordTagRevision(&account, namespace, repo, tagName, digest, pushedAt)
+
+	repoKey := qualifiedRepo(&account, namespace, repo)
+	event := newActivityEvent(account.ID, namespace, models.EventTypePush, pushedAt, repo, tagName, pushEventKey(repoKey, tagName, digest, pushedAt), 1)
+	event.Source = ProviderDockerHub
+	created, err := recordActivity(database.DB, &event)
+	recordWebhookRun(account.ID, namespace, repo, created, err)
+	if err != nil {
+		return fmt.Errorf("failed to record push: %w", err)
+	}
+
+	log.Printf("Webhook push recorded: account=%d repo=%s/%s tag=%s", account.ID, namespace, repo, tagName)
+	return nil
+}
+
+// lookupWebhookTag fetches the pushed tag with the account's token
+func (s *DockerHubService) lookupWebhookTag(ctx context.Context, account *models.DockerAccount, namespace, repo, tagName string) (*DockerHubTag, error) {
+	token, err := utils.Decrypt(account.EncryptedToken, account.TokenIV)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt token: %w", err)
+	}
+	return s.hub.FetchTag(ctx, account.DockerUsername, namespace, repo, tagName, token)
+}
+
+func hashWebhookSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,7 +1,14 @@
 package utils
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
 	"time"
 )
 
@@ -26,3 +33,81 @@ var ShortTimeoutClient = &http.Client{
 		IdleConnTimeout:     60 * time.Second,
 	},
 }
+
+// ErrDestinationNotAllowed is returned when a restricted client refuses to connect to an address
+var ErrDestinationNotAllowed = errors.New("destination address not allowed")
+
+// blockedPrefixes are address ranges outside the public internet that IsPrivate and friends miss
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// NewRestrictedHTTPClient returns a client for URLs supplied by users. With allowedHosts set it
+// only connects to those hosts (which may be private); otherwise it connects to any host whose
+// resolved address is public, refusing loopback, private, link-local and similar ranges. The
+// check runs on the dialed IP, so DNS answers and redirects cannot route around it.
+func NewRestrictedHTTPClient(allowedHosts []string) *http.Client {
+	allowed := make(map[string]bool, len(allowedHosts))
+	for _, host := range allowedHosts {
+		allowed[strings.ToLower(host)] = true
+	}
+
+	direct := &net.Dialer{Timeout: 10 * time.Second}
+	guarded := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil || !IsPublicAddr(ip) {
+				return fmt.Errorf("%w: %s", ErrDestinationNotAllowed, host)
+			}
+			return nil
+		},
+	}
+
+	dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if len(allowed) > 0 {
+			if !allowed[strings.ToLower(host)] && !allowed[strings.ToLower(net.JoinHostPort(host, port))] {
+				return nil, fmt.Errorf("%w: %s", ErrDestinationNotAllowed, host)
+			}
+			return direct.DialContext(ctx, network, address)
+		}
+		return guarded.DialContext(ctx, network, address)
+	}
+
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			Proxy:               nil, // A proxy would dial on our behalf and bypass the address check
+			DialContext:         dial,
+			MaxIdleConns:        50,
+			MaxIdleConnsPerHost: 10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+// IsPublicAddr reports whether ip is a globally routable unicast address
+func IsPublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"docker-heatmap/internal/database"
+)
+
+const (
+	// schedulerLockKey is the Postgres advisory lock held by the replica that runs scheduled jobs
+	schedulerLockKey int64 = 0x646f636b6572 // "docker"
+	// leaderCheckInterval is how often followers try to take over and the leader checks its session
+	leaderCheckInterval = 15 * time.Second
+)
+
+// leaderElector elects one scheduler replica with a session-level advisory lock. The lock
+// lives on a dedicated connection taken out of the pool, so Postgres releases it as soon as
+// the leader's session ends and another replica picks it up on its next check.
+type leaderElector struct {
+	mu   sync.Mutex
+	conn *sql.Conn
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newLeaderElector() *leaderElector {
+	return &leaderElector{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// Start campaigns for leadership now and then keeps checking in the background
+func (e *leaderElector) Start() {
+	e.campaign()
+	go e.run()
+}
+
+func (e *leaderElector) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(leaderCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+			e.campaign()
+		}
+	}
+}
+
+// campaign verifies a held lock is still backed by a live session, or tries to acquire it
+func (e *leaderElector) campaign() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if e.conn != nil {
+		err := e.conn.PingContext(ctx)
+		if err == nil {
+			return
+		}
+		log.Printf("Lost scheduler leadership: %v", err)
+		e.conn.Close()
+		e.conn = nil
+	}
+
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		log.Printf("Failed to get database handle for leader election: %v", err)
+		return
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		log.Printf("Failed to open leader election connection: %v", err)
+		return
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", schedulerLockKey).Scan(&acquired); err != nil {
+		log.Printf("Failed to try scheduler lock: %v", err)
+		conn.Close()
+		return
+	}
+	if !acquired {
+		conn.Close()
+		return
+	}
+
+	e.conn = conn
+	log.Println("Acquired scheduler leadership")
+}
+
+// IsLeader reports whether this replica currently holds the scheduler lock
+func (e *leaderElector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.conn != nil
+}
+
+// Stop ends the campaign and hands leadership over by releasing the lock
+func (e *leaderElector) Stop() {
+	close(e.stop)
+	<-e.done
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := e.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", schedulerLockKey); err != nil {
+		log.Printf("Failed to release scheduler lock: %v", err)
+	}
+	e.conn.Close()
+	e.conn = nil
+	log.Println("Released scheduler leadership")
+}
@@ -0,0 +1,275 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"docker-heatmap/internal/config"
+	"docker-heatmap/internal/database"
+	"docker-heatmap/internal/models"
+	"docker-heatmap/internal/services"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// syncJobPollInterval is how long an idle queue worker waits before looking again
+	syncJobPollInterval = 5 * time.Second
+	// syncJobBusyRetry is when a job blocked by another replica's sync is tried again
+	syncJobBusyRetry = time.Minute
+	// syncJobMaxBackoff caps the exponential retry delay
+	syncJobMaxBackoff = time.Hour
+)
+
+// JobQueue runs queued sync jobs with a pool of workers. Every replica runs one; jobs are
+// claimed with SKIP LOCKED so each is handed to exactly one worker.
+type JobQueue struct {
+	workers       int
+	timeout       time.Duration // Per sync; running jobs are reclaimed after twice as long
+	delay         time.Duration
+	dockerService *services.DockerHubService
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewJobQueue(dockerService *services.DockerHubService) *JobQueue {
+	return &JobQueue{
+		workers:       config.AppConfig.SyncWorkers,
+		timeout:       config.AppConfig.SyncTimeout,
+		delay:         config.AppConfig.SyncJobDelay,
+		dockerService: dockerService,
+		stop:          make(chan struct{}),
+	}
+}
+
+// Start launches the queue workers and the reclaimer of stale jobs
+func (q *JobQueue) Start() {
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+
+	q.wg.Add(1)
+	go q.reclaimStaleJobs()
+
+	log.Printf("Sync job queue started with %d workers", q.workers)
+}
+
+// Stop stops claiming jobs and lets the running ones finish until ctx ends. Syncs still
+// running then are interrupted and their jobs returned to the queue.
+func (q *JobQueue) Stop(ctx context.Context) {
+	close(q.stop)
+	services.DrainSyncs(ctx)
+	q.wg.Wait()
+	log.Println("Sync job queue stopped")
+}
+
+func (q *JobQueue) work() {
+	defer q.wg.Done()
+
+	for {
+		job, err := claimSyncJob()
+		if err != nil {
+			log.Printf("Failed to claim sync job: %v", err)
+		}
+
+		wait := syncJobPollInterval
+		if job != nil {
+			q.runJob(job)
+			wait = q.delay
+		}
+
+		select {
+		case <-q.stop:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// claimSyncJob takes the most urgent due job, or returns nil when there is none
+func claimSyncJob() (*models.SyncJob, error) {
+	var job models.SyncJob
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_at <= NOW()", models.SyncJobStatusPending).
+			Order("priority DESC, run_at, id").
+			Take(&job).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		job.Status = models.SyncJobStatusRunning
+		job.LockedBy = services.InstanceID
+		job.LockedAt = &now
+		job.Attempts++
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"status":    job.Status,
+			"locked_by": job.LockedBy,
+			"locked_at": job.LockedAt,
+			"attempts":  job.Attempts,
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// runJob syncs the job's account and records the outcome
+func (q *JobQueue) runJob(job *models.SyncJob) {
+	log.Printf("Running sync job %d for account %d (trigger=%s, attempt %d/%d)", job.ID, job.DockerAccountID, job.Trigger, job.Attempts, job.MaxAttempts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), q.timeout)
+	err := q.dockerService.SyncActivity(ctx, job.DockerAccountID, job.Trigger)
+	cancel()
+
+	finishSyncJob(job, err)
+}
+
+// finishSyncJob completes, retries or dead-letters a job depending on how its sync ended
+func finishSyncJob(job *models.SyncJob, syncErr error) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"locked_by": "",
+		"locked_at": nil,
+	}
+
+	switch {
+	case syncErr == nil || errors.Is(syncErr, services.ErrSyncCancelled) || errors.Is(syncErr, services.ErrSyncLeaseLost):
+		// A cancelled sync was stopped on purpose, a lost lease means another worker finished it
+		updates["status"] = models.SyncJobStatusDone
+		updates["finished_at"] = now
+		updates["last_error"] = ""
+	case errors.Is(syncErr, services.ErrAccountPaused):
+		// Paused after the job was queued; resuming schedules syncs again
+		updates["status"] = models.SyncJobStatusDone
+		updates["finished_at"] = now
+		updates["last_error"] = syncErr.Error()
+	case errors.Is(syncErr, services.ErrSyncPartial):
+		// The failed namespaces are on the sync run; resyncing the whole account would not fix them
+		updates["status"] = models.SyncJobStatusDone
+		updates["finished_at"] = now
+		updates["last_error"] = syncErr.Error()
+	case errors.Is(syncErr, services.ErrSyncInProgress):
+		// Someone else is syncing the account right now; this attempt does not count
+		updates["status"] = models.SyncJobStatusPending
+		updates["run_at"] = now.Add(syncJobBusyRetry)
+		updates["attempts"] = job.Attempts - 1
+	case errors.Is(syncErr, services.ErrSyncInterrupted) || errors.Is(syncErr, services.ErrSyncShuttingDown):
+		// Cut short by a shutdown: hand the job straight back to the queue for another replica or the restart
+		updates["status"] = models.SyncJobStatusPending
+		updates["run_at"] = now
+		updates["attempts"] = job.Attempts - 1
+		updates["last_error"] = syncErr.Error()
+	case services.IsPermanentSyncError(syncErr) || job.Attempts >= job.MaxAttempts:
+		log.Printf("Sync job %d for account %d dead-lettered: %v", job.ID, job.DockerAccountID, syncErr)
+		updates["status"] = models.SyncJobStatusDead
+		updates["finished_at"] = now
+		updates["last_error"] = syncErr.Error()
+	default:
+		retryAt := now.Add(syncJobBackoff(job.Attempts))
+		log.Printf("Sync job %d for account %d failed, retrying at %s: %v", job.ID, job.DockerAccountID, retryAt.Format(time.RFC3339), syncErr)
+		updates["status"] = models.SyncJobStatusPending
+		updates["run_at"] = retryAt
+		updates["last_error"] = syncErr.Error()
+	}
+
+	// An account has one pending job at most; a request queued meanwhile covers the retry
+	if updates["status"] == models.SyncJobStatusPending && hasPendingSyncJob(job.DockerAccountID) {
+		updates["status"] = models.SyncJobStatusDone
+		updates["finished_at"] = now
+	}
+
+	// Guarded by the claim so a job reclaimed from this worker is left alone
+	err := database.DB.Model(&models.SyncJob{}).
+		Where("id = ? AND status = ? AND locked_by = ?", job.ID, models.SyncJobStatusRunning, job.LockedBy).
+		Updates(updates).Error
+	if err != nil {
+		log.Printf("Failed to update sync job %d: %v", job.ID, err)
+	}
+}
+
+// hasPendingSyncJob reports whether the account already has a job waiting in the queue
+func hasPendingSyncJob(accountID uint) bool {
+	var count int64
+	database.DB.Model(&models.SyncJob{}).
+		Where("docker_account_id = ? AND status = ?", accountID, models.SyncJobStatusPending).
+		Count(&count)
+	return count > 0
+}
+
+// syncJobBackoff doubles the retry delay with every attempt, starting at one minute
+func syncJobBackoff(attempts int) time.Duration {
+	delay := time.Minute
+	for i := 1; i < attempts && delay < syncJobMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > syncJobMaxBackoff {
+		delay = syncJobMaxBackoff
+	}
+	return delay
+}
+
+// reclaimStaleJobs periodically requeues jobs whose worker died before finishing them
+// and closes the sync runs they left open
+func (q *JobQueue) reclaimStaleJobs() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+			staleBefore := time.Now().Add(-2 * q.timeout)
+
+			// Stale jobs of accounts that were queued again since are superseded by the new job
+			err := database.DB.Model(&models.SyncJob{}).
+				Where("status = ? AND locked_at < ?", models.SyncJobStatusRunning, staleBefore).
+				Where("docker_account_id IN (?)", database.DB.Model(&models.SyncJob{}).Select("docker_account_id").Where("status = ?", models.SyncJobStatusPending)).
+				Updates(map[string]interface{}{
+					"status":      models.SyncJobStatusDone,
+					"finished_at": time.Now(),
+					"locked_by":   "",
+					"locked_at":   nil,
+				}).Error
+			if err != nil {
+				log.Printf("Failed to close superseded sync jobs: %v", err)
+				continue
+			}
+
+			result := database.DB.Model(&models.SyncJob{}).
+				Where("status = ? AND locked_at < ?", models.SyncJobStatusRunning, staleBefore).
+				Updates(map[string]interface{}{
+					"status":    models.SyncJobStatusPending,
+					"locked_by": "",
+					"locked_at": nil,
+				})
+			if result.Error != nil {
+				log.Printf("Failed to reclaim stale sync jobs: %v", result.Error)
+			} else if result.RowsAffected > 0 {
+				log.Printf("Requeued %d stale sync jobs", result.RowsAffected)
+			}
+
+			// Runs of a crashed worker would otherwise stay running forever
+			if closed, err := services.InterruptOrphanedSyncRuns(); err != nil {
+				log.Printf("Failed to close orphaned sync runs: %v", err)
+			} else if closed > 0 {
+				log.Printf("Marked %d orphaned sync runs interrupted", closed)
+			}
+		}
+	}
+}
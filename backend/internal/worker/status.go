@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"docker-heatmap/internal/config"
+	"docker-heatmap/internal/database"
+	"docker-heatmap/internal/models"
+	"docker-heatmap/internal/services"
+)
+
+// WorkerStatus reports the effective worker settings of this replica and the state of the job queue
+type WorkerStatus struct {
+	Instance string `json:"instance"`
+	IsLeader bool   `json:"is_leader"`
+
+	SyncScheduleCron string `json:"sync_schedule_cron"`
+	CleanupCron      string `json:"cleanup_cron"`
+	SyncTimeout      string `json:"sync_timeout"`
+	SyncMinInterval  string `json:"sync_min_interval"`
+	SyncJobDelay     string `json:"sync_job_delay"`
+	SyncWorkers      int    `json:"sync_workers"`
+	SyncConcurrency  int    `json:"sync_concurrency"`
+
+	ShutdownGracePeriod string `json:"shutdown_grace_period"`
+
+	// Jobs per status across all replicas
+	Jobs map[models.SyncJobStatus]int64 `json:"jobs"`
+}
+
+// Status collects the worker's configuration, leadership and queue counts
+func (w *SyncWorker) Status() (*WorkerStatus, error) {
+	status := &WorkerStatus{
+		Instance:         services.InstanceID,
+		IsLeader:         w.leader.IsLeader(),
+		SyncScheduleCron: config.AppConfig.SyncScheduleCron,
+		CleanupCron:      config.AppConfig.CleanupCron,
+		SyncTimeout:      config.AppConfig.SyncTimeout.String(),
+		SyncMinInterval:  config.AppConfig.SyncMinInterval.String(),
+		SyncJobDelay:     config.AppConfig.SyncJobDelay.String(),
+		SyncWorkers:      config.AppConfig.SyncWorkers,
+		SyncConcurrency:  config.AppConfig.SyncConcurrency,
+
+		ShutdownGracePeriod: config.AppConfig.ShutdownGracePeriod.String(),
+		Jobs:                make(map[models.SyncJobStatus]int64),
+	}
+
+	var counts []struct {
+		Status models.SyncJobStatus
+		Count  int64
+	}
+	err := database.DB.Model(&models.SyncJob{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range counts {
+		status.Jobs[c.Status] = c.Count
+	}
+
+	return status, nil
+}
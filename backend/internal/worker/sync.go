@@ -5,6 +5,7 @@ import (
 	"log"
 	"time"
 
+	"docker-heatmap/internal/config"
 	"docker-heatmap/internal/database"
 	"docker-heatmap/internal/models"
 	"docker-heatmap/internal/services"
@@ -12,15 +13,23 @@ import (
 	"github.com/robfig/cron/v3"
 )
 
+// dueAccountsBatchSize caps how many due accounts one poll queues; the rest follow a minute later
+const dueAccountsBatchSize = 500
+
 type SyncWorker struct {
 	cron          *cron.Cron
+	leader        *leaderElector
+	queue         *JobQueue
 	dockerService *services.DockerHubService
 }
 
 func NewSyncWorker() *SyncWorker {
+	dockerService := services.NewDockerHubService()
 	return &SyncWorker{
 		cron:          cron.New(),
-		dockerService: services.NewDockerHubService(),
+		leader:        newLeaderElector(),
+		queue:         NewJobQueue(dockerService),
+		dockerService: dockerService,
 	}
 }
 
@@ -28,71 +37,83 @@ func NewSyncWorker() *SyncWorker {
 func (w *SyncWorker) Start() {
 	log.Println("Starting sync worker...")
 
-	// Run cleanup daily at midnight
-	if _, err := w.cron.AddFunc("0 0 * * *", w.cleanupOldData); err != nil {
+	// Every replica schedules the jobs, but only the elected leader runs them
+	w.leader.Start()
+
+	// Run cleanup daily at midnight by default
+	if _, err := w.cron.AddFunc(config.AppConfig.CleanupCron, w.leaderOnly(w.cleanupOldData)); err != nil {
 		log.Printf("Failed to add cleanup cron job: %v", err)
 	}
 
-	// Run scheduled sync for all accounts every 6 hours
-	if _, err := w.cron.AddFunc("0 */6 * * *", w.syncAllAccounts); err != nil {
+	// Poll (every minute by default) for accounts whose next scheduled sync is due
+	if _, err := w.cron.AddFunc(config.AppConfig.SyncScheduleCron, w.leaderOnly(w.enqueueDueAccounts)); err != nil {
 		log.Printf("Failed to add scheduled sync cron job: %v", err)
 	}
 
 	w.cron.Start()
-	log.Println("Sync worker started - (scheduled sync every 6 hours)")
+
+	// Every replica works through the queued syncs
+	w.queue.Start()
+	log.Printf("Sync worker started - (checking for due accounts on %q)", config.AppConfig.SyncScheduleCron)
 }
 
-// Stop gracefully stops the worker
+// Stop gracefully stops the worker, giving running syncs the configured grace period
 func (w *SyncWorker) Stop() {
 	log.Println("Stopping sync worker...")
+	grace, cancel := context.WithTimeout(context.Background(), config.AppConfig.ShutdownGracePeriod)
+	defer cancel()
+
 	ctx := w.cron.Stop()
 	<-ctx.Done()
+	w.queue.Stop(grace)
+	w.leader.Stop()
 	log.Println("Sync worker stopped")
 }
 
-// syncAllAccounts syncs activity for all active Docker accounts
-func (w *SyncWorker) syncAllAccounts() {
-	log.Println("Starting scheduled sync for all accounts...")
+// leaderOnly wraps a cron job so that it is skipped on replicas that are not the leader
+func (w *SyncWorker) leaderOnly(job func()) func() {
+	return func() {
+		// Re-check first so a leader whose session died does not run the job as well
+		w.leader.campaign()
+		if !w.leader.IsLeader() {
+			return
+		}
+		job()
+	}
+}
 
+// enqueueDueAccounts queues a sync for every active Docker account whose next sync is due.
+// Accounts with a queued or running job are left alone until it finishes and reschedules them.
+func (w *SyncWorker) enqueueDueAccounts() {
 	var accounts []models.DockerAccount
-	err := database.DB.Where("is_active = ? AND auto_refresh = ?", true, true).Find(&accounts).Error
+	err := database.DB.Where("is_active = ? AND auto_refresh = ?", true, true).
+		Where("next_sync_at IS NULL OR next_sync_at <= NOW()").
+		Where("NOT EXISTS (SELECT 1 FROM sync_jobs WHERE sync_jobs.docker_account_id = docker_accounts.id AND sync_jobs.status IN ?)",
+			[]models.SyncJobStatus{models.SyncJobStatusPending, models.SyncJobStatusRunning}).
+		Order("next_sync_at NULLS FIRST").
+		Limit(dueAccountsBatchSize).
+		Find(&accounts).Error
 	if err != nil {
-		log.Printf("Failed to fetch accounts: %v", err)
+		log.Printf("Failed to fetch due accounts: %v", err)
+		return
+	}
+	if len(accounts) == 0 {
 		return
 	}
 
-	log.Printf("Found %d accounts to sync", len(accounts))
+	log.Printf("Queueing scheduled syncs for %d due accounts", len(accounts))
 
 	for _, account := range accounts {
 		// Skip if sync is already in progress
-		if account.SyncInProgress {
+		if account.SyncInProgress() {
 			log.Printf("Skipping account %s - sync already in progress", account.DockerUsername)
 			continue
 		}
 
-		// Check if we synced recently (within last 4 hours)
-		if account.LastSyncAt != nil && time.Since(*account.LastSyncAt) < 4*time.Hour {
-			log.Printf("Skipping account %s - synced recently", account.DockerUsername)
-			continue
+		if _, err := w.dockerService.EnqueueSync(account.ID, models.SyncTriggerCron); err != nil {
+			log.Printf("Failed to queue sync for account %s: %v", account.DockerUsername, err)
 		}
-
-		log.Printf("Syncing account: %s", account.DockerUsername)
-
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-		err := w.dockerService.SyncActivity(ctx, account.ID)
-		cancel()
-
-		if err != nil {
-			log.Printf("Failed to sync account %s: %v", account.DockerUsername, err)
-		} else {
-			log.Printf("Successfully synced account: %s", account.DockerUsername)
-		}
-
-		// Small delay between accounts to avoid rate limiting
-		time.Sleep(2 * time.Second)
 	}
-
-	log.Println("Scheduled sync completed")
 }
 
 // cleanupOldData removes activity data older than 1 year
@@ -108,11 +129,45 @@ func (w *SyncWorker) cleanupOldData() {
 	}
 
 	log.Printf("Cleaned up %d old activity records", result.RowsAffected)
+
+	// Only the latest snapshot per repository is needed for pull deltas
+	result = database.DB.Where(
+		"captured_at < ? AND id NOT IN (SELECT MAX(id) FROM repository_pull_snapshots GROUP BY docker_account_id, namespace, repository)",
+		cutoff,
+	).Delete(&models.RepositoryPullSnapshot{})
+	if result.Error != nil {
+		log.Printf("Failed to cleanup old pull snapshots: %v", result.Error)
+		return
+	}
+
+	log.Printf("Cleaned up %d old pull snapshots", result.RowsAffected)
+
+	// Sync history is kept for 90 days
+	runCutoff := time.Now().AddDate(0, 0, -90)
+	if err := database.DB.Where("sync_run_id IN (?)", database.DB.Model(&models.SyncRun{}).Select("id").Where("started_at < ?", runCutoff)).Delete(&models.SyncRunError{}).Error; err != nil {
+		log.Printf("Failed to cleanup old sync run errors: %v", err)
+		return
+	}
+	result = database.DB.Where("started_at < ?", runCutoff).Delete(&models.SyncRun{})
+	if result.Error != nil {
+		log.Printf("Failed to cleanup old sync runs: %v", result.Error)
+		return
+	}
+
+	log.Printf("Cleaned up %d old sync runs", result.RowsAffected)
+
+	// Finished and dead-lettered jobs are kept as long as the run history
+	result = database.DB.Where("status IN ? AND finished_at < ?", []models.SyncJobStatus{models.SyncJobStatusDone, models.SyncJobStatusDead}, runCutoff).Delete(&models.SyncJob{})
+	if result.Error != nil {
+		log.Printf("Failed to cleanup old sync jobs: %v", result.Error)
+		return
+	}
+
+	log.Printf("Cleaned up %d old sync jobs", result.RowsAffected)
 }
 
-// SyncSingleAccount syncs a specific account (for manual triggers)
+// SyncSingleAccount queues a sync of a specific account ahead of scheduled ones (for manual triggers)
 func (w *SyncWorker) SyncSingleAccount(accountID uint) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-	return w.dockerService.SyncActivity(ctx, accountID)
+	_, err := w.dockerService.EnqueueSync(accountID, models.SyncTriggerManual)
+	return err
 }